@@ -1,10 +1,8 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/lab259/go-rscsrv"
@@ -49,24 +47,9 @@ func (*Service1) Stop() error {
 }
 
 func main() {
-	sigs := make(chan os.Signal, 1)
-	done := make(chan bool, 1)
-
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
 	serviceStarter := rscsrv.DefaultServiceStarter(
 		&Service1{},
 		&Service2{},
 	)
-	serviceStarter.Start()
-
-	go func() {
-		sig := <-sigs
-		fmt.Printf("Gracefully stopping because of: %s\n", sig)
-		serviceStarter.Stop(true)
-		done <- true
-	}()
-
-	<-done
-	fmt.Println("Done!")
+	os.Exit(rscsrv.Run(context.Background(), serviceStarter, rscsrv.RunOptions{}))
 }
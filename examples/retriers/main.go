@@ -1,12 +1,11 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	"github.com/lab259/go-rscsrv"
@@ -54,13 +53,8 @@ func (*Service1) Stop() error {
 }
 
 func main() {
-	sigs := make(chan os.Signal, 1)
-	done := make(chan bool, 1)
-
 	rand.Seed(time.Now().Unix())
 
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-
 	retriers := rscsrv.Retriers(rscsrv.StartRetrierOptions{
 		MaxTries:          5,
 		DelayBetweenTries: time.Second,
@@ -73,19 +67,6 @@ func main() {
 			&Service2{},
 		)...,
 	)
-	if err := serviceStarter.Start(); err != nil {
-		fmt.Printf("error starting services: %s\n", err)
-		os.Exit(1)
-	}
-
-	go func() {
-		sig := <-sigs
-		fmt.Printf("Gracefully stopping because of: %s\n", sig)
-		serviceStarter.Stop(true)
-		done <- true
-	}()
-
 	fmt.Println("Hit <Ctrl+C> to stop the service.")
-	<-done
-	fmt.Println("Done!")
+	os.Exit(rscsrv.Run(context.Background(), serviceStarter, rscsrv.RunOptions{}))
 }
@@ -1,6 +1,6 @@
 module github.com/lab259/go-rscsrv
 
-go 1.12
+go 1.13
 
 require (
 	github.com/fatih/color v1.7.0
@@ -0,0 +1,141 @@
+package rscsrv
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	// DefaultLivenessPath is the path of the liveness probe served by
+	// `NewProbeHandler`.
+	DefaultLivenessPath = "/livez"
+	// DefaultReadinessPath is the path of the readiness probe served by
+	// `NewProbeHandler`.
+	DefaultReadinessPath = "/readyz"
+	// DefaultStartupPath is the path of the startup probe served by
+	// `NewProbeHandler`.
+	DefaultStartupPath = "/startupz"
+)
+
+const (
+	probeStatusOK          = "ok"
+	probeStatusUnavailable = "unavailable"
+)
+
+// ProbeHandlerOptions defines the options for `NewProbeHandler`.
+type ProbeHandlerOptions struct {
+	// LivenessPath is where the liveness probe is served. If empty,
+	// `DefaultLivenessPath` will be used.
+	LivenessPath string
+	// ReadinessPath is where the readiness probe is served. If empty,
+	// `DefaultReadinessPath` will be used.
+	ReadinessPath string
+	// StartupPath is where the startup probe is served. If empty,
+	// `DefaultStartupPath` will be used.
+	StartupPath string
+}
+
+// probeResponse is the JSON body written by the probes.
+type probeResponse struct {
+	Status   string            `json:"status"`
+	Services []ServiceSnapshot `json:"services"`
+	Health   *HealthReport     `json:"health,omitempty"`
+}
+
+// NewProbeHandler returns an `http.Handler` serving the liveness, readiness
+// and startup probes of the services of the given `ServiceStarter`.
+//
+// See Also
+//
+// `LivenessHandler`, `ReadinessHandler`, `StartupHandler`
+func NewProbeHandler(starter ServiceStarter, options ProbeHandlerOptions) http.Handler {
+	if options.LivenessPath == "" {
+		options.LivenessPath = DefaultLivenessPath
+	}
+	if options.ReadinessPath == "" {
+		options.ReadinessPath = DefaultReadinessPath
+	}
+	if options.StartupPath == "" {
+		options.StartupPath = DefaultStartupPath
+	}
+	mux := http.NewServeMux()
+	mux.Handle(options.LivenessPath, LivenessHandler(starter))
+	mux.Handle(options.ReadinessPath, ReadinessHandler(starter))
+	mux.Handle(options.StartupPath, StartupHandler(starter))
+	return mux
+}
+
+// LivenessHandler returns an `http.Handler` that responds 503 when a critical
+// service has failed, meaning the process should be restarted.
+func LivenessHandler(starter ServiceStarter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := starter.Snapshot()
+		ok := true
+		for _, service := range snapshot.Services {
+			if service.State == StateFailed && service.Critical {
+				ok = false
+				break
+			}
+		}
+		writeProbe(w, ok, probeResponse{
+			Services: snapshot.Services,
+		})
+	})
+}
+
+// ReadinessHandler returns an `http.Handler` that checks the health of the
+// services and responds 503 while any service is starting or stopping, or
+// when a critical service is not healthy.
+func ReadinessHandler(starter ServiceStarter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := starter.CheckHealth(r.Context())
+		snapshot := starter.Snapshot()
+		ok := report.Healthy()
+		for _, service := range snapshot.Services {
+			switch service.State {
+			case StateConfiguring, StateStarting, StateStopping:
+				ok = false
+			}
+		}
+		writeProbe(w, ok, probeResponse{
+			Services: snapshot.Services,
+			Health:   &report,
+		})
+	})
+}
+
+// StartupHandler returns an `http.Handler` that responds 503 until all
+// services went through their start, and while a critical service is failed.
+func StartupHandler(starter ServiceStarter) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot := starter.Snapshot()
+		ok := true
+		for _, service := range snapshot.Services {
+			switch service.State {
+			case StatePending, StateConfiguring, StateStarting:
+				ok = false
+			case StateFailed:
+				if service.Critical {
+					ok = false
+				}
+			}
+		}
+		writeProbe(w, ok, probeResponse{
+			Services: snapshot.Services,
+		})
+	})
+}
+
+// writeProbe writes the response of a probe as JSON.
+func writeProbe(w http.ResponseWriter, ok bool, response probeResponse) {
+	status := http.StatusOK
+	response.Status = probeStatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+		response.Status = probeStatusUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,118 @@
+package rscsrv_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// probe requests the path from the handler, returning the status code and
+// the decoded body.
+func probe(handler http.Handler, path string) (int, map[string]interface{}) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]interface{}
+	Expect(json.Unmarshal(recorder.Body.Bytes(), &body)).To(Succeed())
+	Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+	return recorder.Code, body
+}
+
+var _ = Describe("ProbeHandler", func() {
+	It("should report all probes as available when services are running", func() {
+		engineStarter := rscsrv.QuietServiceStarter(
+			newMockHealthService("service1", true, nil),
+		)
+		handler := rscsrv.NewProbeHandler(engineStarter, rscsrv.ProbeHandlerOptions{})
+		Expect(engineStarter.Start()).To(Succeed())
+
+		for _, path := range []string{rscsrv.DefaultLivenessPath, rscsrv.DefaultReadinessPath, rscsrv.DefaultStartupPath} {
+			code, body := probe(handler, path)
+			Expect(code).To(Equal(http.StatusOK), path)
+			Expect(body["status"]).To(Equal("ok"))
+			services := body["services"].([]interface{})
+			Expect(services).To(HaveLen(1))
+			Expect(services[0].(map[string]interface{})["state"]).To(Equal("running"))
+		}
+
+		_, body := probe(handler, rscsrv.DefaultReadinessPath)
+		health := body["health"].(map[string]interface{})
+		Expect(health["status"]).To(Equal("healthy"))
+	})
+
+	It("should report unavailable while services are starting", func() {
+		engineStarter := rscsrv.QuietServiceStarter(
+			&MockDependentService{name: "service1", recorder: &orderRecorder{}, MockService: MockService{startDuration: time.Millisecond * 100}},
+		)
+		handler := rscsrv.NewProbeHandler(engineStarter, rscsrv.ProbeHandlerOptions{})
+
+		code, _ := probe(handler, rscsrv.DefaultStartupPath)
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+
+		go func() {
+			defer GinkgoRecover()
+			Eventually(func() rscsrv.ServiceState {
+				return stateOf(engineStarter, "service1")
+			}).Should(Equal(rscsrv.StateStarting))
+			code, body := probe(handler, rscsrv.DefaultReadinessPath)
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(body["status"]).To(Equal("unavailable"))
+			code, _ = probe(handler, rscsrv.DefaultLivenessPath)
+			Expect(code).To(Equal(http.StatusOK))
+		}()
+		Expect(engineStarter.Start()).To(Succeed())
+
+		code, _ = probe(handler, rscsrv.DefaultStartupPath)
+		Expect(code).To(Equal(http.StatusOK))
+	})
+
+	It("should report not ready when a critical service is unhealthy", func() {
+		engineStarter := rscsrv.QuietServiceStarter(
+			newMockHealthService("service1", true, errors.New("connection lost")),
+		)
+		handler := rscsrv.NewProbeHandler(engineStarter, rscsrv.ProbeHandlerOptions{})
+		Expect(engineStarter.Start()).To(Succeed())
+
+		code, body := probe(handler, rscsrv.DefaultReadinessPath)
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		services := body["health"].(map[string]interface{})["services"].([]interface{})
+		Expect(services[0].(map[string]interface{})["error"]).To(Equal("connection lost"))
+
+		code, _ = probe(handler, rscsrv.DefaultLivenessPath)
+		Expect(code).To(Equal(http.StatusOK))
+	})
+
+	It("should report not alive when a critical service failed", func() {
+		engineStarter := rscsrv.QuietServiceStarter(
+			&MockDependentService{name: "service1", recorder: &orderRecorder{}, MockService: MockService{errStart: errors.New("start error")}},
+		)
+		handler := rscsrv.NewProbeHandler(engineStarter, rscsrv.ProbeHandlerOptions{
+			LivenessPath: "/health/live",
+		})
+		Expect(engineStarter.Start()).ToNot(Succeed())
+
+		code, _ := probe(handler, "/health/live")
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		code, _ = probe(handler, rscsrv.DefaultStartupPath)
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("should keep ready while only non critical services are unhealthy", func() {
+		engineStarter := rscsrv.QuietServiceStarter(
+			newMockHealthService("service1", true, nil),
+			newMockHealthService("service2", false, errors.New("connection lost")),
+		)
+		handler := rscsrv.NewProbeHandler(engineStarter, rscsrv.ProbeHandlerOptions{})
+		Expect(engineStarter.Start()).To(Succeed())
+
+		code, body := probe(handler, rscsrv.DefaultReadinessPath)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body["health"].(map[string]interface{})["status"]).To(Equal("degraded"))
+	})
+})
@@ -0,0 +1,187 @@
+package rscsrv
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Exit codes returned by `Run`.
+const (
+	// ExitCodeOK is returned when the services were started and stopped
+	// without errors.
+	ExitCodeOK = 0
+	// ExitCodeStartError is returned when a service fails to start, or the
+	// deadline of the context is reached before the services are started.
+	ExitCodeStartError = 1
+	// ExitCodeConfigurationError is returned when a service fails to load,
+	// validate or apply its configuration.
+	ExitCodeConfigurationError = 2
+	// ExitCodeFatal is returned when the `ServiceStarter` shuts down by
+	// itself (see `ServiceStarter.Fatal`).
+	ExitCodeFatal = 3
+	// ExitCodeStopError is returned when a service fails to stop.
+	ExitCodeStopError = 4
+	// ExitCodeStopTimeout is returned when the services do not stop before
+	// the deadline.
+	ExitCodeStopTimeout = 5
+	// ExitCodeForced is returned when a second stop signal is received while
+	// stopping.
+	ExitCodeForced = DefaultForceExitCode
+)
+
+// DefaultRunStopTimeout is the deadline for stopping all services used by
+// `Run` when no `RunOptions.StopTimeout` is provided.
+const DefaultRunStopTimeout = time.Second * 30
+
+// RunOptions configures `Run`.
+type RunOptions struct {
+	// Actions maps the signals to the actions taken when they are received.
+	// If empty, the same defaults of `NewSignalStarter` will be used.
+	Actions map[os.Signal]SignalAction
+
+	// Reporter receives the snapshots dumped by `SignalDump`. If nil, the
+	// reporter of the starter will be used when it is a `SnapshotReporter`.
+	Reporter SnapshotReporter
+
+	// StopTimeout is the deadline for stopping all services. If zero,
+	// `DefaultRunStopTimeout` will be used.
+	StopTimeout time.Duration
+
+	// ForceExitCode is returned when a second stop signal is received while
+	// stopping. If zero, `ExitCodeForced` will be used.
+	ForceExitCode int
+}
+
+// Run starts the services with the context and waits for a stop signal, the
+// cancellation of the context or a fatal error of the `ServiceStarter`. Then,
+// it stops the services within the `RunOptions.StopTimeout` and returns the
+// exit code for the process. If the start fails, or the deadline of the
+// context is reached before the services are started, the services already
+// started are stopped as well:
+//
+//	func main() {
+//		os.Exit(rscsrv.Run(context.Background(), serviceStarter, rscsrv.RunOptions{}))
+//	}
+func Run(ctx context.Context, starter ServiceStarter, options RunOptions) int {
+	if len(options.Actions) == 0 {
+		options.Actions = defaultSignalActions()
+	}
+	if options.StopTimeout == 0 {
+		options.StopTimeout = DefaultRunStopTimeout
+	}
+	if options.ForceExitCode == 0 {
+		options.ForceExitCode = ExitCodeForced
+	}
+
+	signals := make(chan os.Signal, 1)
+	signalList := make([]os.Signal, 0, len(options.Actions))
+	for sig := range options.Actions {
+		signalList = append(signalList, sig)
+	}
+	signal.Notify(signals, signalList...)
+	defer signal.Stop(signals)
+
+	startCh := make(chan error, 1)
+	go func() {
+		startCh <- starter.StartWithContext(ctx)
+	}()
+
+	var (
+		fatalCh     <-chan error
+		stopCh      chan error
+		exitCode    = ExitCodeOK
+		startFailed bool
+		forced      bool
+	)
+	stop := func() {
+		if stopCh != nil {
+			// A second stop signal.
+			forced = true
+			return
+		}
+		stopCh = make(chan error, 1)
+		go func() {
+			stopCtx, cancelFunc := context.WithTimeout(context.Background(), options.StopTimeout)
+			defer cancelFunc()
+			stopCh <- starter.StopWithContext(stopCtx, true)
+		}()
+	}
+
+	done := ctx.Done()
+	for {
+		select {
+		case err := <-startCh:
+			startCh = nil
+			if err == nil {
+				fatalCh = starter.Fatal()
+				continue
+			}
+			if stopCh != nil {
+				// The start was cancelled by the stop.
+				continue
+			}
+			switch ctx.Err() {
+			case nil:
+				startFailed = true
+				select {
+				case <-starter.Fatal():
+					// The start was cancelled by the shut down.
+					exitCode = ExitCodeFatal
+				default:
+					exitCode = startExitCode(err)
+				}
+			case context.DeadlineExceeded:
+				// The services did not start in time.
+				startFailed = true
+				exitCode = ExitCodeStartError
+			}
+			// The services already started are stopped as well.
+			stop()
+		case <-fatalCh:
+			fatalCh = nil
+			exitCode = ExitCodeFatal
+			if stopCh == nil {
+				stop()
+			}
+		case <-done:
+			done = nil
+			if startCh != nil && ctx.Err() == context.DeadlineExceeded {
+				// The services did not start in time.
+				startFailed = true
+				exitCode = ExitCodeStartError
+			}
+			if stopCh == nil {
+				stop()
+			}
+		case sig := <-signals:
+			handleSignal(starter, options.Actions[sig], options.Reporter, stop)
+			if forced {
+				return options.ForceExitCode
+			}
+		case err := <-stopCh:
+			if err == nil || startFailed {
+				return exitCode
+			}
+			if errors.Is(err, ErrStopTimeout) {
+				return ExitCodeStopTimeout
+			}
+			return ExitCodeStopError
+		}
+	}
+}
+
+// startExitCode maps the error returned by `ServiceStarter.Start` to an exit
+// code.
+func startExitCode(err error) int {
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		switch serviceErr.Phase {
+		case PhaseLoadConfiguration, PhaseValidateConfiguration, PhaseApplyConfiguration:
+			return ExitCodeConfigurationError
+		}
+	}
+	return ExitCodeStartError
+}
@@ -0,0 +1,51 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package rscsrv_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+var _ = Describe("Run signals", func() {
+	options := rscsrv.RunOptions{
+		Actions: map[os.Signal]rscsrv.SignalAction{
+			syscall.SIGUSR2: rscsrv.SignalStop,
+		},
+	}
+
+	It("should stop the services when receiving a stop signal", func() {
+		service := &MockService{}
+		exitCode := make(chan int, 1)
+		go func() {
+			exitCode <- rscsrv.Run(context.Background(), rscsrv.QuietServiceStarter(service), options)
+		}()
+
+		Eventually(service.started.Load).Should(BeTrue())
+		Expect(syscall.Kill(os.Getpid(), syscall.SIGUSR2)).To(Succeed())
+		Eventually(exitCode).Should(Receive(Equal(rscsrv.ExitCodeOK)))
+		Expect(service.stopped.Load()).To(BeTrue())
+	})
+
+	It("should force the exit when receiving a second stop signal while stopping", func() {
+		service := &MockService{stopDuration: time.Millisecond * 200}
+		exitCode := make(chan int, 1)
+		go func() {
+			exitCode <- rscsrv.Run(context.Background(), rscsrv.QuietServiceStarter(service), options)
+		}()
+
+		Eventually(service.started.Load).Should(BeTrue())
+		Expect(syscall.Kill(os.Getpid(), syscall.SIGUSR2)).To(Succeed())
+		Eventually(service.stopped.Load).Should(BeTrue())
+		Expect(syscall.Kill(os.Getpid(), syscall.SIGUSR2)).To(Succeed())
+		Eventually(exitCode).Should(Receive(Equal(rscsrv.ExitCodeForced)))
+	})
+})
@@ -0,0 +1,150 @@
+package rscsrv_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+var _ = Describe("Run", func() {
+	It("should stop the services when the context is cancelled", func() {
+		service := &MockService{}
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		exitCode := make(chan int, 1)
+		go func() {
+			exitCode <- rscsrv.Run(ctx, rscsrv.QuietServiceStarter(service), rscsrv.RunOptions{})
+		}()
+
+		Eventually(service.started.Load).Should(BeTrue())
+		cancelFunc()
+		Eventually(exitCode).Should(Receive(Equal(rscsrv.ExitCodeOK)))
+		Expect(service.stopped.Load()).To(BeTrue())
+	})
+
+	It("should pass the context down to the services", func() {
+		recorder := &orderRecorder{}
+		service := &MockServiceWithContextValues{recorder: recorder}
+		ctx, cancelFunc := context.WithCancel(context.WithValue(context.Background(), contextKey("request"), "42"))
+		exitCode := make(chan int, 1)
+		go func() {
+			exitCode <- rscsrv.Run(ctx, rscsrv.QuietServiceStarter(service), rscsrv.RunOptions{})
+		}()
+
+		Eventually(recorder.Events).Should(Equal([]string{
+			"load 42",
+			"start 42",
+			"run 42",
+		}))
+		cancelFunc()
+		Eventually(exitCode).Should(Receive(Equal(rscsrv.ExitCodeOK)))
+	})
+
+	It("should cancel the start when the context is cancelled", func() {
+		service := &MockServiceWithCancellation{}
+		service.startDuration = time.Second
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		time.AfterFunc(time.Millisecond*25, cancelFunc)
+
+		Expect(rscsrv.Run(ctx, rscsrv.QuietServiceStarter(service), rscsrv.RunOptions{})).To(Equal(rscsrv.ExitCodeOK))
+		Expect(service.started.Load()).To(BeFalse())
+	})
+
+	It("should return the start error exit code when the deadline is reached while starting", func() {
+		service := &MockServiceWithCancellation{}
+		service.startDuration = time.Second
+		ctx, cancelFunc := context.WithTimeout(context.Background(), time.Millisecond*25)
+		defer cancelFunc()
+
+		Expect(rscsrv.Run(ctx, rscsrv.QuietServiceStarter(service), rscsrv.RunOptions{})).To(Equal(rscsrv.ExitCodeStartError))
+		Expect(service.started.Load()).To(BeFalse())
+	})
+
+	It("should return the OK exit code when the deadline is reached after starting", func() {
+		ctx, cancelFunc := context.WithTimeout(context.Background(), time.Millisecond*25)
+		defer cancelFunc()
+
+		Expect(rscsrv.Run(ctx, rscsrv.QuietServiceStarter(&MockService{}), rscsrv.RunOptions{})).To(Equal(rscsrv.ExitCodeOK))
+	})
+
+	It("should return the start error exit code", func() {
+		service := &MockService{errStart: errors.New("start error")}
+		exitCode := rscsrv.Run(context.Background(), rscsrv.QuietServiceStarter(service), rscsrv.RunOptions{})
+		Expect(exitCode).To(Equal(rscsrv.ExitCodeStartError))
+	})
+
+	It("should stop the services already started when the start fails", func() {
+		service1 := &MockService{}
+		service2 := &MockService{errStart: errors.New("start error")}
+		exitCode := rscsrv.Run(context.Background(), rscsrv.QuietServiceStarter(service1, service2), rscsrv.RunOptions{})
+		Expect(exitCode).To(Equal(rscsrv.ExitCodeStartError))
+		Expect(service1.stopped.Load()).To(BeTrue())
+	})
+
+	It("should return the configuration error exit code", func() {
+		service := &MockService{errApplyConfiguration: errors.New("apply error")}
+		exitCode := rscsrv.Run(context.Background(), rscsrv.QuietServiceStarter(service), rscsrv.RunOptions{})
+		Expect(exitCode).To(Equal(rscsrv.ExitCodeConfigurationError))
+	})
+
+	It("should return the fatal exit code when the starter shuts down by itself", func() {
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: rscsrv.SupervisorOptions{
+				Backoff:     time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+				MaxRestarts: 1,
+			},
+		}, &MockRunnableService{name: "consumer", failures: -1})
+
+		exitCode := rscsrv.Run(context.Background(), engineStarter, rscsrv.RunOptions{})
+		Expect(exitCode).To(Equal(rscsrv.ExitCodeFatal))
+	})
+
+	It("should return the fatal exit code when the starter shuts down while starting", func() {
+		service := &MockServiceWithCancellation{}
+		service.startDuration = time.Second
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: rscsrv.SupervisorOptions{
+				Backoff:     time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+				MaxRestarts: 1,
+			},
+		}, &MockRunnableService{name: "consumer", failures: -1}, service)
+
+		exitCode := rscsrv.Run(context.Background(), engineStarter, rscsrv.RunOptions{})
+		Expect(exitCode).To(Equal(rscsrv.ExitCodeFatal))
+		Expect(service.started.Load()).To(BeFalse())
+	})
+
+	It("should return the stop error exit code", func() {
+		service := &MockService{errStop: errors.New("stop error")}
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		exitCode := make(chan int, 1)
+		go func() {
+			exitCode <- rscsrv.Run(ctx, rscsrv.QuietServiceStarter(service), rscsrv.RunOptions{})
+		}()
+
+		Eventually(service.started.Load).Should(BeTrue())
+		cancelFunc()
+		Eventually(exitCode).Should(Receive(Equal(rscsrv.ExitCodeStopError)))
+	})
+
+	It("should return the stop timeout exit code", func() {
+		service := &MockService{stopDuration: time.Millisecond * 200}
+		ctx, cancelFunc := context.WithCancel(context.Background())
+		exitCode := make(chan int, 1)
+		go func() {
+			exitCode <- rscsrv.Run(ctx, rscsrv.QuietServiceStarter(service), rscsrv.RunOptions{
+				StopTimeout: time.Millisecond * 10,
+			})
+		}()
+
+		Eventually(service.started.Load).Should(BeTrue())
+		cancelFunc()
+		Eventually(exitCode).Should(Receive(Equal(rscsrv.ExitCodeStopTimeout)))
+	})
+})
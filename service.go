@@ -12,6 +12,14 @@ var (
 	// ErrServiceNotRunning is the error returned when a non started server is
 	// stopped or restarted.
 	ErrServiceNotRunning = errors.New("service not running")
+
+	// ErrStopTimeout is the error returned when a service, or the whole
+	// shutdown, does not finish stopping before its deadline.
+	ErrStopTimeout = errors.New("stop timeout")
+
+	// ErrHealthCheckTimeout is the error reported when a health check does
+	// not finish before its deadline.
+	ErrHealthCheckTimeout = errors.New("health check timeout")
 )
 
 // Service abstracts the precense of a the name in a possible `Startable` or
@@ -36,6 +44,14 @@ type Stoppable interface {
 	Stop() error
 }
 
+// StoppableWithContext is an abstraction for implementing services that
+// can have its Stop process cancelled.
+type StoppableWithContext interface {
+	// StopWithContext stops the service with a context that gets cancelled
+	// when the stop deadline is reached.
+	StopWithContext(ctx context.Context) error
+}
+
 // StartableWithContext is an abstraction for implementing services that
 // can have its Start process cancelled.
 type StartableWithContext interface {
@@ -43,6 +59,15 @@ type StartableWithContext interface {
 	StartWithContext(ctx context.Context) error
 }
 
+// Runnable is an abstraction for long-running services, like consumers and
+// schedulers, that keep working after being started.
+type Runnable interface {
+	// Run runs the service until ctx is cancelled. Returning before that,
+	// with or without an error, means the service stopped working and it is
+	// handled by the supervisor of the `ServiceStarter`.
+	Run(ctx context.Context) error
+}
+
 // Configurable is an abstraction for implement loading and applying
 // configuration.
 type Configurable interface {
@@ -54,3 +79,75 @@ type Configurable interface {
 	// will be returned, otherwise the error.
 	ApplyConfiguration(interface{}) error
 }
+
+// Dependent is an abstraction for services that can only be started after
+// other services.
+type Dependent interface {
+	// DependsOn returns the names of the services that must be started
+	// before this one (and stopped after it).
+	DependsOn() []string
+}
+
+// HealthChecker is an abstraction for services that can report whether they
+// are still healthy after being started.
+type HealthChecker interface {
+	// Check returns nil if the service is healthy, otherwise the error that
+	// describes the problem. The ctx gets cancelled when the check timeout is
+	// reached.
+	Check(ctx context.Context) error
+}
+
+// Critical is an abstraction for services that can declare whether the
+// application can work without them. Services that do not implement it are
+// considered critical.
+type Critical interface {
+	// Critical returns false if the application can keep working, degraded,
+	// while the service is not healthy.
+	Critical() bool
+}
+
+// ConfigurationValidator is an optional abstraction for `Configurable`
+// services that can check a configuration before it is applied.
+type ConfigurationValidator interface {
+	// ValidateConfiguration returns an error if the configuration cannot be
+	// applied to the service.
+	ValidateConfiguration(conf interface{}) error
+}
+
+// ConfigurableWithContext is an optional abstraction for `Configurable`
+// services that can have the loading of their configuration cancelled.
+type ConfigurableWithContext interface {
+	// LoadConfigurationWithContext loads the configuration with a context that
+	// gets cancelled when the start is cancelled. It is used instead of
+	// `LoadConfiguration`.
+	LoadConfigurationWithContext(ctx context.Context) (interface{}, error)
+}
+
+// Reconfigurable is an opt-in abstraction for `Configurable` services that
+// can have a new configuration applied while running.
+type Reconfigurable interface {
+	Configurable
+
+	// Reconfigurable returns true if `ApplyConfiguration` can be called while
+	// the service is running.
+	Reconfigurable() bool
+}
+
+// BackgroundStartable is an abstraction for services whose start may go on
+// in background after `Start` returns, like a `StartRetrier` with the
+// `Background` option. The `ServiceStarter` does not wait for them: they stay
+// `StateStartingInBackground` until the start finishes.
+type BackgroundStartable interface {
+	// StartingInBackground returns a channel that receives the result of the
+	// start in background, or nil if the last start did not go to background.
+	StartingInBackground() <-chan error
+}
+
+// Wrapper is an abstraction for services that decorate another service,
+// like the `StartRetrier`. The `ServiceStarter` looks for the abstractions it
+// uses in the wrapper first and, when it does not implement them, in the
+// wrapped services.
+type Wrapper interface {
+	// Unwrap returns the wrapped service.
+	Unwrap() Service
+}
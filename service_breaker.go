@@ -0,0 +1,208 @@
+package rscsrv
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error returned by a `CircuitBreaker` that is not
+// letting the calls through.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitState is the state of a `CircuitBreaker`.
+type CircuitState string
+
+const (
+	// CircuitClosed lets all calls through.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen fails all calls with `ErrCircuitOpen`, until the cooldown
+	// is over.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single trial call through. Its success closes
+	// the circuit, its failure opens it again.
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreakerReporter is the abstraction for reporting the transitions of
+// a `CircuitBreaker`.
+type CircuitBreakerReporter interface {
+	// ReportCircuitBreaker is called whenever the state of the breaker
+	// changes. err is the failure that opened the circuit, if any.
+	ReportCircuitBreaker(breaker *CircuitBreaker, from, to CircuitState, err error)
+}
+
+// CircuitBreakerOptions defines the options for the `CircuitBreaker`.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures, of starts or
+	// health checks, that opens the circuit. If 0, 5 will be used.
+	FailureThreshold int
+
+	// Cooldown is for how long the circuit stays open before letting a trial
+	// call through. If 0, 30 seconds will be used.
+	Cooldown time.Duration
+
+	// Reporter receives the transitions of the breaker. If nil, the reporter
+	// of the `ServiceStarter` the breaker is given to will be used, when it
+	// is a `CircuitBreakerReporter`, or `DefaultColorStarterReporter`
+	// otherwise.
+	Reporter CircuitBreakerReporter
+}
+
+// CircuitBreaker is a `Wrapper` that stops calling the `Start` and `Check` of
+// a service that keeps failing, so a dependency that is down is not hammered
+// (eg: by a `StartRetrier` wrapping the breaker).
+type CircuitBreaker struct {
+	Service
+	options CircuitBreakerOptions
+
+	mutex    sync.Mutex
+	reporter CircuitBreakerReporter
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trying   bool
+}
+
+// NewCircuitBreaker returns a `CircuitBreaker` wrapping the service.
+func NewCircuitBreaker(service Service, options CircuitBreakerOptions) *CircuitBreaker {
+	if options.FailureThreshold == 0 {
+		options.FailureThreshold = 5
+	}
+	if options.Cooldown == 0 {
+		options.Cooldown = 30 * time.Second
+	}
+	reporter := options.Reporter
+	if reporter == nil {
+		reporter = DefaultColorStarterReporter
+	}
+	return &CircuitBreaker{
+		Service:  service,
+		options:  options,
+		reporter: reporter,
+		state:    CircuitClosed,
+	}
+}
+
+// useReporter makes the breaker report to the reporter of the
+// `ServiceStarter`, unless a reporter was given in the options.
+func (breaker *CircuitBreaker) useReporter(reporter CircuitBreakerReporter) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	if breaker.options.Reporter == nil {
+		breaker.reporter = reporter
+	}
+}
+
+// Unwrap returns the service protected by the breaker.
+func (breaker *CircuitBreaker) Unwrap() Service {
+	return breaker.Service
+}
+
+// State returns the current state of the breaker.
+func (breaker *CircuitBreaker) State() CircuitState {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	return breaker.state
+}
+
+// Start starts the service, unless the circuit is open.
+func (breaker *CircuitBreaker) Start() error {
+	return breaker.StartWithContext(context.Background())
+}
+
+// StartWithContext starts the service, unless the circuit is open.
+func (breaker *CircuitBreaker) StartWithContext(ctx context.Context) error {
+	return breaker.call(ctx, func() error {
+		switch startable := findStartable(breaker.Service).(type) {
+		case StartableWithContext:
+			return startable.StartWithContext(ctx)
+		case Startable:
+			return startable.Start()
+		}
+		return nil
+	})
+}
+
+// Check checks the health of the service, unless the circuit is open. If the
+// service is not a `HealthChecker`, nil is returned and the circuit is left
+// untouched.
+func (breaker *CircuitBreaker) Check(ctx context.Context) error {
+	var checker HealthChecker
+	if !As(breaker.Service, &checker) {
+		return nil
+	}
+	return breaker.call(ctx, func() error {
+		return checker.Check(ctx)
+	})
+}
+
+// call runs fnc if the circuit lets it through, and records its result.
+// Calls cancelled by ctx are not counted.
+func (breaker *CircuitBreaker) call(ctx context.Context, fnc func() error) error {
+	if err := breaker.allow(); err != nil {
+		return err
+	}
+	err := fnc()
+	if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+		breaker.mutex.Lock()
+		breaker.trying = false
+		breaker.mutex.Unlock()
+		return err
+	}
+	breaker.record(err)
+	return err
+}
+
+// allow returns nil if the call can go through, or `ErrCircuitOpen`.
+func (breaker *CircuitBreaker) allow() error {
+	breaker.mutex.Lock()
+	switch breaker.state {
+	case CircuitOpen:
+		if time.Since(breaker.openedAt) < breaker.options.Cooldown {
+			breaker.mutex.Unlock()
+			return ErrCircuitOpen
+		}
+		breaker.state = CircuitHalfOpen
+		breaker.trying = true
+		reporter := breaker.reporter
+		breaker.mutex.Unlock()
+		reporter.ReportCircuitBreaker(breaker, CircuitOpen, CircuitHalfOpen, nil)
+		return nil
+	case CircuitHalfOpen:
+		defer breaker.mutex.Unlock()
+		// Only one trial at a time.
+		if breaker.trying {
+			return ErrCircuitOpen
+		}
+		breaker.trying = true
+		return nil
+	}
+	breaker.mutex.Unlock()
+	return nil
+}
+
+// record updates the state of the breaker with the result of a call.
+func (breaker *CircuitBreaker) record(err error) {
+	breaker.mutex.Lock()
+	from := breaker.state
+	breaker.trying = false
+	if err == nil {
+		breaker.failures = 0
+		breaker.state = CircuitClosed
+	} else {
+		breaker.failures++
+		if from == CircuitHalfOpen || breaker.failures >= breaker.options.FailureThreshold {
+			breaker.state = CircuitOpen
+			breaker.openedAt = time.Now()
+		}
+	}
+	to := breaker.state
+	reporter := breaker.reporter
+	breaker.mutex.Unlock()
+
+	if from != to {
+		reporter.ReportCircuitBreaker(breaker, from, to, err)
+	}
+}
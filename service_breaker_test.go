@@ -0,0 +1,187 @@
+package rscsrv_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// breakerRecorder is a `CircuitBreakerReporter` that records the
+// transitions.
+type breakerRecorder struct {
+	mutex       sync.Mutex
+	transitions []string
+}
+
+func (reporter *breakerRecorder) ReportCircuitBreaker(breaker *rscsrv.CircuitBreaker, from, to rscsrv.CircuitState, err error) {
+	reporter.mutex.Lock()
+	reporter.transitions = append(reporter.transitions, string(from)+" -> "+string(to))
+	reporter.mutex.Unlock()
+}
+
+func (reporter *breakerRecorder) Transitions() []string {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	return append([]string{}, reporter.transitions...)
+}
+
+// breakerStarterReporter is a `ServiceStarterReporter` that records the
+// transitions of the breakers.
+type breakerStarterReporter struct {
+	rscsrv.NopStarterReporter
+	breakerRecorder
+}
+
+var _ = Describe("CircuitBreaker", func() {
+	errConnection := errors.New("connection refused")
+
+	It("should open after consecutive start failures", func() {
+		reporter := &breakerRecorder{}
+		service := &retrierErrorService{err: errConnection}
+		breaker := rscsrv.NewCircuitBreaker(service, rscsrv.CircuitBreakerOptions{
+			FailureThreshold: 3,
+			Cooldown:         time.Minute,
+			Reporter:         reporter,
+		})
+
+		for i := 0; i < 3; i++ {
+			Expect(breaker.State()).To(Equal(rscsrv.CircuitClosed))
+			Expect(breaker.Start()).To(MatchError(errConnection))
+		}
+		Expect(breaker.State()).To(Equal(rscsrv.CircuitOpen))
+		Expect(breaker.Start()).To(MatchError(rscsrv.ErrCircuitOpen))
+		Expect(service.startCount).To(Equal(3))
+		Expect(reporter.Transitions()).To(Equal([]string{"closed -> open"}))
+	})
+
+	It("should reset the failures after a success", func() {
+		service := &retrierErrorService{err: errConnection}
+		breaker := rscsrv.NewCircuitBreaker(service, rscsrv.CircuitBreakerOptions{
+			FailureThreshold: 2,
+			Reporter:         &breakerRecorder{},
+		})
+
+		Expect(breaker.Start()).To(HaveOccurred())
+		service.err = nil
+		Expect(breaker.Start()).To(Succeed())
+		service.err = errConnection
+		Expect(breaker.Start()).To(HaveOccurred())
+		Expect(breaker.State()).To(Equal(rscsrv.CircuitClosed))
+	})
+
+	It("should half open after the cooldown", func() {
+		reporter := &breakerRecorder{}
+		service := &retrierErrorService{err: errConnection}
+		breaker := rscsrv.NewCircuitBreaker(service, rscsrv.CircuitBreakerOptions{
+			FailureThreshold: 1,
+			Cooldown:         time.Millisecond * 20,
+			Reporter:         reporter,
+		})
+
+		Expect(breaker.Start()).To(MatchError(errConnection))
+		Expect(breaker.Start()).To(MatchError(rscsrv.ErrCircuitOpen))
+
+		// The trial fails, so the circuit opens again.
+		time.Sleep(time.Millisecond * 25)
+		Expect(breaker.Start()).To(MatchError(errConnection))
+		Expect(breaker.State()).To(Equal(rscsrv.CircuitOpen))
+
+		// The trial succeeds, so the circuit closes.
+		time.Sleep(time.Millisecond * 25)
+		service.err = nil
+		Expect(breaker.Start()).To(Succeed())
+		Expect(breaker.State()).To(Equal(rscsrv.CircuitClosed))
+		Expect(service.startCount).To(Equal(3))
+		Expect(reporter.Transitions()).To(Equal([]string{
+			"closed -> open",
+			"open -> half-open",
+			"half-open -> open",
+			"open -> half-open",
+			"half-open -> closed",
+		}))
+	})
+
+	It("should open after consecutive health check failures", func() {
+		service := newMockHealthService("database", true, errConnection)
+		breaker := rscsrv.NewCircuitBreaker(service, rscsrv.CircuitBreakerOptions{
+			FailureThreshold: 2,
+			Cooldown:         time.Minute,
+			Reporter:         &breakerRecorder{},
+		})
+
+		Expect(breaker.Check(context.Background())).To(MatchError(errConnection))
+		Expect(breaker.Check(context.Background())).To(MatchError(errConnection))
+		Expect(breaker.Check(context.Background())).To(MatchError(rscsrv.ErrCircuitOpen))
+		Expect(service.checks.Load()).To(Equal(int32(2)))
+		Expect(breaker.Start()).To(MatchError(rscsrv.ErrCircuitOpen))
+	})
+
+	It("should not count the health checks of services that cannot be checked", func() {
+		service := &retrierErrorService{err: errConnection}
+		breaker := rscsrv.NewCircuitBreaker(service, rscsrv.CircuitBreakerOptions{
+			FailureThreshold: 2,
+			Cooldown:         time.Minute,
+			Reporter:         &breakerRecorder{},
+		})
+
+		Expect(breaker.Start()).To(MatchError(errConnection))
+		Expect(breaker.Check(context.Background())).To(Succeed())
+		Expect(breaker.Start()).To(MatchError(errConnection))
+		Expect(breaker.State()).To(Equal(rscsrv.CircuitOpen))
+	})
+
+	It("should not count cancelled calls", func() {
+		service := newMockHealthService("database", true, nil)
+		service.checkDelay = time.Second
+		breaker := rscsrv.NewCircuitBreaker(service, rscsrv.CircuitBreakerOptions{
+			FailureThreshold: 1,
+			Reporter:         &breakerRecorder{},
+		})
+
+		ctx, cancelFunc := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancelFunc()
+		Expect(breaker.Check(ctx)).To(MatchError(context.DeadlineExceeded))
+		Expect(breaker.State()).To(Equal(rscsrv.CircuitClosed))
+	})
+
+	It("should report to the reporter of the starter", func() {
+		reporter := &breakerStarterReporter{}
+		engineStarter := rscsrv.NewServiceStarter(reporter,
+			rscsrv.NewCircuitBreaker(&retrierErrorService{err: errConnection}, rscsrv.CircuitBreakerOptions{
+				FailureThreshold: 1,
+			}),
+		)
+		Expect(engineStarter.Start()).To(HaveOccurred())
+		Expect(reporter.Transitions()).To(Equal([]string{"closed -> open"}))
+	})
+
+	It("should be retried by a StartRetrier", func() {
+		reporter := &breakerRecorder{}
+		service := &retrierMockService{successAt: 3}
+		engineStarter := rscsrv.NewServiceStarter(
+			&rscsrv.NopStarterReporter{},
+			rscsrv.NewStartRetrier(rscsrv.NewCircuitBreaker(service, rscsrv.CircuitBreakerOptions{
+				FailureThreshold: 2,
+				Cooldown:         time.Millisecond * 20,
+				Reporter:         reporter,
+			}), rscsrv.StartRetrierOptions{
+				DelayBetweenTries: time.Millisecond * 5,
+				Reporter:          &retrierMockReporter{},
+			}),
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(service.startCount).To(Equal(3))
+		Expect(service.loadConfCount).To(Equal(1))
+		Expect(reporter.Transitions()).To(Equal([]string{
+			"closed -> open",
+			"open -> half-open",
+			"half-open -> closed",
+		}))
+	})
+})
@@ -0,0 +1,152 @@
+package rscsrv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DependencyCycleError is the error returned when the dependencies declared
+// by the services (through `Dependent`) form a cycle.
+type DependencyCycleError struct {
+	// Cycle lists the names of the services involved in the cycle. The first
+	// and the last names are the same.
+	Cycle []string
+}
+
+func (err *DependencyCycleError) Error() string {
+	return fmt.Sprintf("dependency cycle detected: %s", strings.Join(err.Cycle, " -> "))
+}
+
+// DependencyNotFoundError is the error returned when a service depends on a
+// name that was not provided to the `ServiceStarter`.
+type DependencyNotFoundError struct {
+	// Service is the name of the service that declared the dependency.
+	Service string
+	// Dependency is the name that could not be found.
+	Dependency string
+}
+
+func (err *DependencyNotFoundError) Error() string {
+	return fmt.Sprintf("service %q depends on %q, which was not provided", err.Service, err.Dependency)
+}
+
+// serviceNode is a service in the dependency graph built by the
+// `ServiceStarter`.
+type serviceNode struct {
+	// index is the position of the service in the list provided to
+	// `buildServiceGraph`.
+	index        int
+	service      Service
+	status       *serviceStatus
+	dependencies []*serviceNode
+	dependents   []*serviceNode
+
+	// conf is the configuration applied to the service, if any.
+	conf       interface{}
+	configured bool
+
+	// loaded is the configuration loaded before starting any service, when
+	// `ServiceStarterOptions.TransactionalConfiguration` is set.
+	loaded    interface{}
+	preloaded bool
+
+	// runCancel and runDone control the `Run` of `Runnable` services.
+	runCancel context.CancelFunc
+	runDone   chan struct{}
+
+	// backgroundFailed marks a service whose start in background failed, so
+	// there is nothing to stop.
+	backgroundFailed bool
+	// background is the start in background of the service, if any.
+	background *backgroundStart
+}
+
+// backgroundStart is the start of a service that goes on in background.
+type backgroundStart struct {
+	// done is closed when the start finishes, with its error in err.
+	done chan struct{}
+	err  error
+}
+
+const (
+	nodeUnvisited = iota
+	nodeVisiting
+	nodeVisited
+)
+
+// buildServiceGraph resolves the dependencies of the given services and
+// returns them sorted topologically: every service comes after all of its
+// dependencies. Services that do not depend on each other keep the order they
+// were provided.
+func buildServiceGraph(services []Service) ([]*serviceNode, error) {
+	nodes := make([]*serviceNode, len(services))
+	byName := make(map[string][]int, len(services))
+	for i, srv := range services {
+		nodes[i] = &serviceNode{
+			index:   i,
+			service: srv,
+		}
+		byName[srv.Name()] = append(byName[srv.Name()], i)
+	}
+
+	marks := make([]int, len(services))
+	path := make([]int, 0, len(services))
+	sorted := make([]*serviceNode, 0, len(services))
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch marks[i] {
+		case nodeVisited:
+			return nil
+		case nodeVisiting:
+			// The service is already in the path, so the path from its first
+			// appearance until now is a cycle.
+			cycle := make([]string, 0, len(path)+1)
+			for j := len(path) - 1; j >= 0; j-- {
+				if path[j] == i {
+					for _, k := range path[j:] {
+						cycle = append(cycle, services[k].Name())
+					}
+					break
+				}
+			}
+			return &DependencyCycleError{
+				Cycle: append(cycle, services[i].Name()),
+			}
+		}
+
+		marks[i] = nodeVisiting
+		path = append(path, i)
+		var dependent Dependent
+		if As(services[i], &dependent) {
+			for _, name := range dependent.DependsOn() {
+				deps, ok := byName[name]
+				if !ok {
+					return &DependencyNotFoundError{
+						Service:    services[i].Name(),
+						Dependency: name,
+					}
+				}
+				for _, j := range deps {
+					if err := visit(j); err != nil {
+						return err
+					}
+					nodes[i].dependencies = append(nodes[i].dependencies, nodes[j])
+					nodes[j].dependents = append(nodes[j].dependents, nodes[i])
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		marks[i] = nodeVisited
+		sorted = append(sorted, nodes[i])
+		return nil
+	}
+
+	for i := range services {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,105 @@
+package rscsrv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Phase identifies a step of the lifecycle of a service.
+type Phase string
+
+const (
+	// PhaseLoadConfiguration is the phase where `LoadConfiguration` is called.
+	PhaseLoadConfiguration Phase = "load configuration"
+	// PhaseValidateConfiguration is the phase where `ValidateConfiguration`
+	// is called.
+	PhaseValidateConfiguration Phase = "validate configuration"
+	// PhaseApplyConfiguration is the phase where `ApplyConfiguration` is
+	// called.
+	PhaseApplyConfiguration Phase = "apply configuration"
+	// PhaseStart is the phase where the service is started.
+	PhaseStart Phase = "start"
+	// PhaseStartAttempt is an attempt of a `StartRetrier` to start the
+	// service.
+	PhaseStartAttempt Phase = "start attempt"
+	// PhaseStop is the phase where the service is stopped.
+	PhaseStop Phase = "stop"
+	// PhaseRun is the phase where a `Runnable` service is running.
+	PhaseRun Phase = "run"
+)
+
+// ServiceError is the error returned by the `ServiceStarter` when a service
+// fails. It identifies the service and the phase that failed.
+//
+// The original error can be retrieved with `errors.Is` and `errors.As`.
+type ServiceError struct {
+	// Service is the name of the service that failed.
+	Service string
+	// Phase is the lifecycle phase that failed.
+	Phase Phase
+	// Err is the error returned by the service.
+	Err error
+}
+
+func (err *ServiceError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", err.Service, err.Phase, err.Err)
+}
+
+// Unwrap returns the error returned by the service.
+func (err *ServiceError) Unwrap() error {
+	return err.Err
+}
+
+// MultiError is a list of errors reported at once. `errors.Is` and
+// `errors.As` match any of the errors in the list.
+type MultiError []error
+
+func (errs MultiError) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(errs), strings.Join(messages, "; "))
+}
+
+// Is reports whether any of the errors matches target.
+func (errs MultiError) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target.
+func (errs MultiError) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the errors in the list.
+func (errs MultiError) Unwrap() []error {
+	return errs
+}
+
+// newServiceError wraps err in a `ServiceError`. nil is returned if err is
+// nil.
+func newServiceError(srv Service, phase Phase, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ServiceError{
+		Service: srv.Name(),
+		Phase:   phase,
+		Err:     err,
+	}
+}
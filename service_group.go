@@ -0,0 +1,98 @@
+package rscsrv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ServiceGroup is a group of services started, stopped and supervised
+// together by its own `ServiceStarter`, that can be nested into another
+// `ServiceStarter` as a single `Service`. This allows building supervision
+// trees.
+//
+// The group is `Runnable`: when its services cannot be kept running (the
+// restart budget of the group is exceeded), its `Run` returns the error and
+// the parent supervisor restarts the whole group according to its own
+// options.
+type ServiceGroup struct {
+	name    string
+	starter ServiceStarter
+
+	mutex   sync.Mutex
+	running bool
+}
+
+// NewServiceGroup returns a new `ServiceGroup` with the given name, that
+// manages the services according to the options (eg: `Strategy` and
+// `Supervisor`).
+func NewServiceGroup(name string, options ServiceStarterOptions, services ...Service) *ServiceGroup {
+	return &ServiceGroup{
+		name:    name,
+		starter: NewServiceStarterWithOptions(options, services...),
+	}
+}
+
+// Name returns the name of the group.
+func (group *ServiceGroup) Name() string {
+	return group.name
+}
+
+// StartWithContext starts all services of the group. If any of them fails,
+// the ones already started are stopped.
+func (group *ServiceGroup) StartWithContext(ctx context.Context) error {
+	if err := group.starter.StartWithContext(ctx); err != nil {
+		group.starter.Stop(true)
+		return err
+	}
+
+	group.mutex.Lock()
+	group.running = true
+	group.mutex.Unlock()
+	return nil
+}
+
+// Stop stops all services of the group.
+func (group *ServiceGroup) Stop() error {
+	group.mutex.Lock()
+	running := group.running
+	group.running = false
+	group.mutex.Unlock()
+	if !running {
+		return nil
+	}
+	return group.starter.Stop(true)
+}
+
+// Run waits until ctx is cancelled or the services of the group cannot be
+// kept running, in which case the error is returned.
+func (group *ServiceGroup) Run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-group.starter.Fatal():
+		return err
+	}
+}
+
+// Check returns an error if any critical service of the group is not
+// healthy.
+func (group *ServiceGroup) Check(ctx context.Context) error {
+	report := group.starter.CheckHealth(ctx)
+	if report.Healthy() {
+		return nil
+	}
+	unhealthy := make([]string, 0, len(report.Services))
+	for _, service := range report.Services {
+		if service.Critical && !service.Healthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s: %s", service.Name, service.Error))
+		}
+	}
+	return fmt.Errorf("unhealthy services: %s", strings.Join(unhealthy, "; "))
+}
+
+// Snapshot returns the current state of the services of the group.
+func (group *ServiceGroup) Snapshot() Snapshot {
+	return group.starter.Snapshot()
+}
@@ -0,0 +1,100 @@
+package rscsrv_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+var _ = Describe("ServiceGroup", func() {
+	It("should start and stop the services of the group", func() {
+		recorder := &orderRecorder{}
+		group := rscsrv.NewServiceGroup("storage", rscsrv.ServiceStarterOptions{},
+			&MockDependentService{name: "database", recorder: recorder},
+			&MockDependentService{name: "cache", recorder: recorder},
+		)
+		engineStarter := rscsrv.QuietServiceStarter(
+			group,
+			&MockDependentService{name: "api", dependsOn: []string{"storage"}, recorder: recorder},
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(stateOf(engineStarter, "storage")).To(Equal(rscsrv.StateRunning))
+		Expect(group.Snapshot().Services).To(HaveLen(2))
+		Expect(group.Check(context.Background())).To(Succeed())
+
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		Expect(recorder.Events()).To(Equal([]string{
+			"start database",
+			"start cache",
+			"start api",
+			"stop api",
+			"stop cache",
+			"stop database",
+		}))
+	})
+
+	It("should stop the services already started when one of them fails", func() {
+		recorder := &orderRecorder{}
+		database := &MockDependentService{name: "database", recorder: recorder}
+		group := rscsrv.NewServiceGroup("storage", rscsrv.ServiceStarterOptions{},
+			database,
+			&MockDependentService{name: "cache", recorder: recorder, MockService: MockService{errStart: errors.New("start error")}},
+		)
+		engineStarter := rscsrv.QuietServiceStarter(group)
+		err := engineStarter.Start()
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*rscsrv.ServiceError).Service).To(Equal("storage"))
+		Expect(database.stopped.Load()).To(BeTrue())
+	})
+
+	It("should report the unhealthy services of the group", func() {
+		group := rscsrv.NewServiceGroup("storage", rscsrv.ServiceStarterOptions{},
+			newMockHealthService("database", true, errors.New("connection lost")),
+		)
+		engineStarter := rscsrv.QuietServiceStarter(group)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		report := engineStarter.CheckHealth(context.Background())
+		Expect(report.Status).To(Equal(rscsrv.HealthStatusUnhealthy))
+		Expect(report.Services[0].Error).To(Equal("unhealthy services: database: connection lost"))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should be restarted by the parent when its restart budget is exceeded", func() {
+		recorder := &orderRecorder{}
+		consumer := &MockRunnableService{name: "consumer", failures: 3}
+		group := rscsrv.NewServiceGroup("storage", rscsrv.ServiceStarterOptions{
+			Supervisor: rscsrv.SupervisorOptions{
+				Backoff:     time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+				MaxRestarts: 1,
+			},
+		},
+			&MockDependentService{name: "database", recorder: recorder},
+			consumer,
+		)
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: rscsrv.SupervisorOptions{
+				Backoff:    time.Millisecond,
+				MaxBackoff: time.Millisecond,
+			},
+		}, group)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		Eventually(consumer.running.Load).Should(BeTrue())
+		Expect(consumer.runs.Load()).To(Equal(int32(4)))
+		Expect(recorder.Events()).To(Equal([]string{
+			"start database",
+			"stop database",
+			"start database",
+		}))
+		Expect(stateOf(engineStarter, "storage")).To(Equal(rscsrv.StateRunning))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		Expect(consumer.running.Load()).To(BeFalse())
+	})
+})
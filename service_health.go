@@ -0,0 +1,177 @@
+package rscsrv
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultHealthCheckTimeout is the timeout of each health check when
+// `ServiceStarterOptions.HealthCheckTimeout` is not defined.
+const defaultHealthCheckTimeout = 5 * time.Second
+
+// HealthStatus is the aggregated health of the services.
+type HealthStatus string
+
+const (
+	// HealthStatusHealthy means all services are healthy.
+	HealthStatusHealthy HealthStatus = "healthy"
+	// HealthStatusDegraded means only non critical services are unhealthy.
+	HealthStatusDegraded HealthStatus = "degraded"
+	// HealthStatusUnhealthy means at least one critical service is unhealthy.
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+// ServiceHealth is the health of a service.
+type ServiceHealth struct {
+	// Name is the name of the service.
+	Name string `json:"name"`
+	// State is the lifecycle state of the service when it was checked.
+	State ServiceState `json:"state"`
+	// Critical tells whether the application can work without this service.
+	// See `Critical`.
+	Critical bool `json:"critical"`
+	// Healthy tells whether the service is running and its `HealthChecker`,
+	// if any, reported no error.
+	Healthy bool `json:"healthy"`
+	// Error describes why the service is not healthy.
+	Error string `json:"error,omitempty"`
+	// CheckedAt is when the service was checked.
+	CheckedAt time.Time `json:"checked_at"`
+	// Duration is how long the `HealthChecker` took.
+	Duration time.Duration `json:"duration"`
+	// Cached tells whether the result was reused from a previous check.
+	Cached bool `json:"cached"`
+}
+
+// HealthReport is the health of all services of a `ServiceStarter`.
+type HealthReport struct {
+	// Status is the aggregated health of the services.
+	Status HealthStatus `json:"status"`
+	// Time is when the report was created.
+	Time time.Time `json:"time"`
+	// Services lists the health of each service, in the order they were
+	// provided to the `ServiceStarter`.
+	Services []ServiceHealth `json:"services"`
+}
+
+// Healthy returns true if no critical service is unhealthy.
+func (report HealthReport) Healthy() bool {
+	return report.Status != HealthStatusUnhealthy
+}
+
+// healthCache keeps the last health check result of each service.
+type healthCache struct {
+	mutex   sync.Mutex
+	results map[*serviceStatus]ServiceHealth
+}
+
+func (cache *healthCache) get(status *serviceStatus, ttl time.Duration) (ServiceHealth, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	result, ok := cache.results[status]
+	if !ok || time.Since(result.CheckedAt) > ttl {
+		return ServiceHealth{}, false
+	}
+	return result, true
+}
+
+func (cache *healthCache) set(status *serviceStatus, result ServiceHealth) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.results == nil {
+		cache.results = make(map[*serviceStatus]ServiceHealth)
+	}
+	cache.results[status] = result
+}
+
+// CheckHealth checks, concurrently, the health of all services.
+//
+// Services that are not running are unhealthy. Running services are healthy
+// unless their `HealthChecker` reports an error or does not finish within the
+// `HealthCheckTimeout`. Results younger than `HealthCheckCacheTTL` are reused.
+func (engineStarter *serviceStarter) CheckHealth(ctx context.Context) HealthReport {
+	report := HealthReport{
+		Status:   HealthStatusHealthy,
+		Time:     time.Now(),
+		Services: make([]ServiceHealth, len(engineStarter.services)),
+	}
+
+	var wg sync.WaitGroup
+	for i, srv := range engineStarter.services {
+		wg.Add(1)
+		go func(i int, srv Service) {
+			defer wg.Done()
+			report.Services[i] = engineStarter.checkService(ctx, srv, engineStarter.statuses[i])
+		}(i, srv)
+	}
+	wg.Wait()
+
+	for _, health := range report.Services {
+		if health.Healthy {
+			continue
+		}
+		if health.Critical {
+			report.Status = HealthStatusUnhealthy
+			break
+		}
+		report.Status = HealthStatusDegraded
+	}
+	return report
+}
+
+// checkService checks the health of a single service.
+func (engineStarter *serviceStarter) checkService(ctx context.Context, srv Service, status *serviceStatus) ServiceHealth {
+	health := ServiceHealth{
+		Name:      status.name,
+		State:     status.State(),
+		Critical:  status.critical,
+		CheckedAt: time.Now(),
+	}
+
+	if health.State != StateRunning {
+		health.Error = fmt.Sprintf("service is %s", health.State)
+		return health
+	}
+
+	var checker HealthChecker
+	if !As(srv, &checker) {
+		health.Healthy = true
+		return health
+	}
+
+	if cached, ok := engineStarter.health.get(status, engineStarter.options.HealthCheckCacheTTL); ok {
+		cached.State = health.State
+		cached.Cached = true
+		return cached
+	}
+
+	timeout := engineStarter.options.HealthCheckTimeout
+	if timeout == 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancelFunc := context.WithTimeout(ctx, timeout)
+	defer cancelFunc()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.Check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ErrHealthCheckTimeout
+	}
+
+	health.Duration = time.Since(health.CheckedAt)
+	health.Healthy = err == nil
+	if err != nil {
+		health.Error = err.Error()
+	}
+	if engineStarter.options.HealthCheckCacheTTL > 0 {
+		engineStarter.health.set(status, health)
+	}
+	return health
+}
@@ -0,0 +1,134 @@
+package rscsrv_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/atomic"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+type MockHealthService struct {
+	MockDependentService
+	critical   bool
+	checkErr   error
+	checkDelay time.Duration
+	checks     atomic.Int32
+}
+
+func (service *MockHealthService) Critical() bool {
+	return service.critical
+}
+
+func (service *MockHealthService) Check(ctx context.Context) error {
+	service.checks.Inc()
+	select {
+	case <-time.After(service.checkDelay):
+		return service.checkErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newMockHealthService(name string, critical bool, checkErr error) *MockHealthService {
+	return &MockHealthService{
+		MockDependentService: MockDependentService{
+			name:     name,
+			recorder: &orderRecorder{},
+		},
+		critical: critical,
+		checkErr: checkErr,
+	}
+}
+
+var _ = Describe("Health", func() {
+	It("should report healthy services", func() {
+		engineStarter := rscsrv.QuietServiceStarter(
+			newMockHealthService("service1", true, nil),
+			&MockDependentService{name: "service2", recorder: &orderRecorder{}},
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		report := engineStarter.CheckHealth(context.Background())
+		Expect(report.Status).To(Equal(rscsrv.HealthStatusHealthy))
+		Expect(report.Healthy()).To(BeTrue())
+		Expect(report.Services).To(HaveLen(2))
+		Expect(report.Services[0].Name).To(Equal("service1"))
+		Expect(report.Services[0].Healthy).To(BeTrue())
+		Expect(report.Services[1].Healthy).To(BeTrue())
+		Expect(report.Services[1].Critical).To(BeTrue())
+	})
+
+	It("should report unhealthy when a critical service fails its check", func() {
+		engineStarter := rscsrv.QuietServiceStarter(
+			newMockHealthService("service1", true, errors.New("connection lost")),
+			newMockHealthService("service2", false, nil),
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		report := engineStarter.CheckHealth(context.Background())
+		Expect(report.Status).To(Equal(rscsrv.HealthStatusUnhealthy))
+		Expect(report.Healthy()).To(BeFalse())
+		Expect(report.Services[0].Error).To(Equal("connection lost"))
+	})
+
+	It("should report degraded when only non critical services are unhealthy", func() {
+		engineStarter := rscsrv.QuietServiceStarter(
+			newMockHealthService("service1", true, nil),
+			newMockHealthService("service2", false, errors.New("connection lost")),
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		report := engineStarter.CheckHealth(context.Background())
+		Expect(report.Status).To(Equal(rscsrv.HealthStatusDegraded))
+		Expect(report.Healthy()).To(BeTrue())
+	})
+
+	It("should report services that are not running as unhealthy", func() {
+		service := newMockHealthService("service1", true, nil)
+		engineStarter := rscsrv.QuietServiceStarter(service)
+
+		report := engineStarter.CheckHealth(context.Background())
+		Expect(report.Status).To(Equal(rscsrv.HealthStatusUnhealthy))
+		Expect(report.Services[0].State).To(Equal(rscsrv.StatePending))
+		Expect(report.Services[0].Error).To(Equal("service is pending"))
+		Expect(service.checks.Load()).To(BeZero())
+	})
+
+	It("should run the checks concurrently and respect the timeout", func() {
+		service1 := newMockHealthService("service1", true, nil)
+		service1.checkDelay = time.Second
+		service2 := newMockHealthService("service2", true, nil)
+		service2.checkDelay = time.Millisecond * 20
+		service3 := newMockHealthService("service3", true, nil)
+		service3.checkDelay = time.Millisecond * 20
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			HealthCheckTimeout: time.Millisecond * 100,
+		}, service1, service2, service3)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		startedAt := time.Now()
+		report := engineStarter.CheckHealth(context.Background())
+		Expect(time.Since(startedAt)).To(BeNumerically("<", time.Millisecond*300))
+		Expect(report.Status).To(Equal(rscsrv.HealthStatusUnhealthy))
+		Expect(report.Services[0].Healthy).To(BeFalse())
+		Expect(report.Services[1].Healthy).To(BeTrue())
+		Expect(report.Services[2].Healthy).To(BeTrue())
+	})
+
+	It("should reuse cached results", func() {
+		service := newMockHealthService("service1", true, nil)
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			HealthCheckCacheTTL: time.Minute,
+		}, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		Expect(engineStarter.CheckHealth(context.Background()).Services[0].Cached).To(BeFalse())
+		Expect(engineStarter.CheckHealth(context.Background()).Services[0].Cached).To(BeTrue())
+		Expect(service.checks.Load()).To(Equal(int32(1)))
+	})
+})
@@ -0,0 +1,157 @@
+package rscsrv
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrStartInProgress is the error returned by `Reload` when the services are
+// still being started.
+var ErrStartInProgress = errors.New("start in progress")
+
+// Reload loads the configuration of the running `Configurable` services
+// again. Services whose configuration did not change are skipped.
+//
+// The new configuration is applied, while running, to the services that are
+// `Reconfigurable`. Other services are restarted with the new configuration
+// if `ServiceStarterOptions.RestartOnReload` is set.
+//
+// A failure does not interrupt the reload of the other services, unless
+// `ServiceStarterOptions.TransactionalConfiguration` is set. All failures are
+// returned in a `MultiError`.
+//
+// If the services are still being started, `ErrStartInProgress` is returned
+// and nothing is reloaded.
+func (engineStarter *serviceStarter) Reload() error {
+	// Services cannot be stopped, or started, while reloading.
+	engineStarter.stopMutex.Lock()
+	defer engineStarter.stopMutex.Unlock()
+	engineStarter.restartMutex.Lock()
+	defer engineStarter.restartMutex.Unlock()
+
+	if engineStarter.starting {
+		return ErrStartInProgress
+	}
+
+	if engineStarter.options.TransactionalConfiguration {
+		return engineStarter.reloadTransaction()
+	}
+
+	var errs MultiError
+	for _, node := range engineStarter.nodes {
+		conf, changed, err := engineStarter.reloadConfiguration(node)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if err := engineStarter.applyReload(node, conf); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// reloadChange is a configuration to be applied by a transactional reload.
+type reloadChange struct {
+	node     *serviceNode
+	conf     interface{}
+	previous interface{}
+}
+
+// reloadTransaction loads the configuration of all running services and
+// applies it only if all of them succeed. If any `ApplyConfiguration` fails,
+// the previous configurations are applied back, in the reverse order.
+func (engineStarter *serviceStarter) reloadTransaction() error {
+	var errs MultiError
+	var changes []reloadChange
+	for _, node := range engineStarter.nodes {
+		conf, changed, err := engineStarter.reloadConfiguration(node)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if changed {
+			changes = append(changes, reloadChange{node, conf, node.conf})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	for i, change := range changes {
+		err := engineStarter.applyReload(change.node, change.conf)
+		if err == nil {
+			continue
+		}
+		errs = append(errs, err)
+		for j := i; j >= 0; j-- {
+			rollback := changes[j]
+			if err := engineStarter.applyReload(rollback.node, rollback.previous); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+	return nil
+}
+
+// reloadConfiguration loads the configuration of a running service again.
+// changed reports whether the configuration differs from the applied one and
+// can be applied.
+func (engineStarter *serviceStarter) reloadConfiguration(node *serviceNode) (conf interface{}, changed bool, err error) {
+	if _, ok := asConfigurable(node.service); !ok || node.status.State() != StateRunning {
+		return nil, false, nil
+	}
+
+	engineStarter.reporter.BeforeBegin(node.service)
+	conf, err = engineStarter.loadConfiguration(engineStarter.detachedCtx, node)
+	if err != nil {
+		return nil, false, err
+	}
+	if node.configured && reflect.DeepEqual(node.conf, conf) {
+		return nil, false, nil
+	}
+	return conf, isReconfigurable(node.service) || engineStarter.options.RestartOnReload, nil
+}
+
+// applyReload applies the configuration to the service, while running if it
+// is `Reconfigurable`, or restarting it otherwise.
+func (engineStarter *serviceStarter) applyReload(node *serviceNode, conf interface{}) error {
+	srv := node.service
+	if isReconfigurable(srv) {
+		if err := engineStarter.applyConfiguration(engineStarter.detachedCtx, node, conf); err != nil {
+			return newServiceError(srv, PhaseApplyConfiguration, err)
+		}
+		return nil
+	}
+
+	// A service that failed restarting is not running anymore.
+	if node.status.State() == StateRunning {
+		ctx, cancelFunc := engineStarter.stopContext(context.Background())
+		defer cancelFunc()
+		if err := engineStarter.stopService(ctx, node); err != nil {
+			return err
+		}
+	}
+
+	if err := engineStarter.applyConfiguration(engineStarter.detachedCtx, node, conf); err != nil {
+		node.status.set(StateFailed, err)
+		return newServiceError(srv, PhaseApplyConfiguration, err)
+	}
+	_, err := engineStarter.startNode(engineStarter.detachedCtx, node)
+	return err
+}
+
+// isReconfigurable returns true if the service can apply a new configuration
+// while running.
+func isReconfigurable(srv Service) bool {
+	var reconfigurable Reconfigurable
+	return As(srv, &reconfigurable) && reconfigurable.Reconfigurable()
+}
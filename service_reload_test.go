@@ -0,0 +1,302 @@
+package rscsrv_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+type MockReconfigurableService struct {
+	MockDependentService
+	mutex       sync.Mutex
+	conf        interface{}
+	applied     []interface{}
+	live        bool
+	errApply    error
+	errValidate error
+}
+
+func newMockReconfigurableService(name string, live bool, recorder *orderRecorder) *MockReconfigurableService {
+	return &MockReconfigurableService{
+		MockDependentService: MockDependentService{name: name, recorder: recorder},
+		conf:                 "v1",
+		live:                 live,
+	}
+}
+
+func (service *MockReconfigurableService) setConfiguration(conf interface{}) {
+	service.mutex.Lock()
+	service.conf = conf
+	service.mutex.Unlock()
+}
+
+func (service *MockReconfigurableService) Applied() []interface{} {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	return append([]interface{}{}, service.applied...)
+}
+
+func (service *MockReconfigurableService) LoadConfiguration() (interface{}, error) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	return service.conf, service.errLoadingConfiguration
+}
+
+func (service *MockReconfigurableService) ApplyConfiguration(conf interface{}) error {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	if service.errApply != nil {
+		return service.errApply
+	}
+	service.applied = append(service.applied, conf)
+	return nil
+}
+
+func (service *MockReconfigurableService) ValidateConfiguration(interface{}) error {
+	return service.errValidate
+}
+
+func (service *MockReconfigurableService) Reconfigurable() bool {
+	return service.live
+}
+
+var _ = Describe("Reload", func() {
+	It("should apply the new configuration to running services", func() {
+		recorder := &orderRecorder{}
+		service := newMockReconfigurableService("cache", true, recorder)
+		reporter := &countEngineReporter{}
+		engineStarter := rscsrv.NewServiceStarter(reporter, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		service.setConfiguration("v2")
+		Expect(engineStarter.Reload()).To(Succeed())
+		Expect(service.Applied()).To(Equal([]interface{}{"v1", "v2"}))
+		Expect(reporter.countAfterLoadConfiguration).To(Equal(2))
+		Expect(reporter.countAfterApplyConfiguration).To(Equal(2))
+		Expect(recorder.Events()).To(Equal([]string{"start cache"}))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should skip services whose configuration did not change", func() {
+		service := newMockReconfigurableService("cache", true, &orderRecorder{})
+		reporter := &countEngineReporter{}
+		engineStarter := rscsrv.NewServiceStarter(reporter, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		Expect(engineStarter.Reload()).To(Succeed())
+		Expect(service.Applied()).To(Equal([]interface{}{"v1"}))
+		Expect(reporter.countAfterLoadConfiguration).To(Equal(2))
+		Expect(reporter.countAfterApplyConfiguration).To(Equal(1))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should ignore the new configuration of services that cannot apply it live", func() {
+		recorder := &orderRecorder{}
+		service := newMockReconfigurableService("database", false, recorder)
+		engineStarter := rscsrv.QuietServiceStarter(service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		service.setConfiguration("v2")
+		Expect(engineStarter.Reload()).To(Succeed())
+		Expect(service.Applied()).To(Equal([]interface{}{"v1"}))
+		Expect(recorder.Events()).To(Equal([]string{"start database"}))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should restart services that cannot apply the configuration live", func() {
+		recorder := &orderRecorder{}
+		service := newMockReconfigurableService("database", false, recorder)
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			RestartOnReload: true,
+		}, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		service.setConfiguration("v2")
+		Expect(engineStarter.Reload()).To(Succeed())
+		Expect(service.Applied()).To(Equal([]interface{}{"v1", "v2"}))
+		Expect(recorder.Events()).To(Equal([]string{
+			"start database",
+			"stop database",
+			"start database",
+		}))
+		Expect(stateOf(engineStarter, "database")).To(Equal(rscsrv.StateRunning))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should keep reloading the other services when one of them fails", func() {
+		recorder := &orderRecorder{}
+		database := newMockReconfigurableService("database", true, recorder)
+		cache := newMockReconfigurableService("cache", true, recorder)
+		engineStarter := rscsrv.QuietServiceStarter(database, cache)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		database.setConfiguration("v2")
+		database.errApply = errors.New("apply error")
+		cache.setConfiguration("v2")
+		err := engineStarter.Reload()
+		Expect(err).To(MatchError("database: apply configuration: apply error"))
+		Expect(cache.Applied()).To(Equal([]interface{}{"v1", "v2"}))
+		Expect(stateOf(engineStarter, "database")).To(Equal(rscsrv.StateRunning))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should not reload services that are not running", func() {
+		service := newMockReconfigurableService("cache", true, &orderRecorder{})
+		engineStarter := rscsrv.QuietServiceStarter(service)
+
+		service.setConfiguration("v2")
+		Expect(engineStarter.Reload()).To(Succeed())
+		Expect(service.Applied()).To(BeEmpty())
+	})
+
+	It("should not reload while the services are being started", func() {
+		recorder := &orderRecorder{}
+		cache := newMockReconfigurableService("cache", true, recorder)
+		slow := &MockDependentService{name: "slow", recorder: recorder, MockService: MockService{startDuration: time.Millisecond * 100}}
+		engineStarter := rscsrv.QuietServiceStarter(cache, slow)
+
+		done := make(chan error)
+		go func() {
+			done <- engineStarter.Start()
+		}()
+		Eventually(func() rscsrv.ServiceState {
+			return stateOf(engineStarter, "slow")
+		}).Should(Equal(rscsrv.StateStarting))
+		cache.setConfiguration("v2")
+		Expect(engineStarter.Reload()).To(MatchError(rscsrv.ErrStartInProgress))
+		Expect(<-done).To(Succeed())
+
+		Expect(engineStarter.Reload()).To(Succeed())
+		Expect(cache.Applied()).To(Equal([]interface{}{"v1", "v2"}))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should wait for the supervisor to restart the services", func() {
+		cache := newMockReconfigurableService("cache", true, &orderRecorder{})
+		cache.stopDuration = time.Millisecond * 100
+		consumer := &MockRunnableService{name: "consumer", failures: 1}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: rscsrv.SupervisorOptions{
+				Backoff:    time.Millisecond,
+				MaxBackoff: time.Millisecond,
+			},
+			Strategy: rscsrv.OneForAll,
+		}, cache, consumer)
+		Expect(engineStarter.Start()).To(Succeed())
+		Eventually(func() rscsrv.ServiceState {
+			return stateOf(engineStarter, "cache")
+		}).Should(Equal(rscsrv.StateStopping))
+
+		cache.setConfiguration("v2")
+		Expect(engineStarter.Reload()).To(Succeed())
+		Expect(stateOf(engineStarter, "cache")).To(Equal(rscsrv.StateRunning))
+		Expect(cache.Applied()).To(Equal([]interface{}{"v1", "v2"}))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	Context("Transactional", func() {
+		options := rscsrv.ServiceStarterOptions{
+			TransactionalConfiguration: true,
+			RestartOnReload:            true,
+		}
+
+		It("should load the configuration of all services before starting any", func() {
+			recorder := &orderRecorder{}
+			database := newMockReconfigurableService("database", true, recorder)
+			cache := newMockReconfigurableService("cache", true, recorder)
+			cache.errLoadingConfiguration = errors.New("load error")
+			engineStarter := rscsrv.NewServiceStarterWithOptions(options, database, cache)
+
+			err := engineStarter.Start()
+			Expect(err).To(MatchError("cache: load configuration: load error"))
+			Expect(recorder.Events()).To(BeEmpty())
+			Expect(database.Applied()).To(BeEmpty())
+			Expect(stateOf(engineStarter, "database")).To(Equal(rscsrv.StatePending))
+			Expect(stateOf(engineStarter, "cache")).To(Equal(rscsrv.StateFailed))
+		})
+
+		It("should validate the configuration of all services before starting any", func() {
+			recorder := &orderRecorder{}
+			database := newMockReconfigurableService("database", true, recorder)
+			database.errValidate = errors.New("invalid host")
+			cache := newMockReconfigurableService("cache", true, recorder)
+			cache.errValidate = errors.New("invalid size")
+			engineStarter := rscsrv.NewServiceStarterWithOptions(options, database, cache)
+
+			err := engineStarter.Start()
+			Expect(err).To(Equal(rscsrv.MultiError{
+				&rscsrv.ServiceError{Service: "database", Phase: rscsrv.PhaseValidateConfiguration, Err: database.errValidate},
+				&rscsrv.ServiceError{Service: "cache", Phase: rscsrv.PhaseValidateConfiguration, Err: cache.errValidate},
+			}))
+			Expect(recorder.Events()).To(BeEmpty())
+		})
+
+		It("should start the services with the loaded configuration", func() {
+			recorder := &orderRecorder{}
+			database := newMockReconfigurableService("database", false, recorder)
+			reporter := &countEngineReporter{}
+			options := options
+			options.Reporter = reporter
+			engineStarter := rscsrv.NewServiceStarterWithOptions(options, database)
+
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(database.Applied()).To(Equal([]interface{}{"v1"}))
+			Expect(reporter.countAfterLoadConfiguration).To(Equal(1))
+			Expect(recorder.Events()).To(Equal([]string{"start database"}))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+
+		It("should not apply any configuration when one of them fails to load", func() {
+			database := newMockReconfigurableService("database", true, &orderRecorder{})
+			cache := newMockReconfigurableService("cache", true, &orderRecorder{})
+			engineStarter := rscsrv.NewServiceStarterWithOptions(options, database, cache)
+			Expect(engineStarter.Start()).To(Succeed())
+
+			database.setConfiguration("v2")
+			cache.setConfiguration("v2")
+			cache.errValidate = errors.New("invalid size")
+			Expect(engineStarter.Reload()).To(MatchError("cache: validate configuration: invalid size"))
+			Expect(database.Applied()).To(Equal([]interface{}{"v1"}))
+			Expect(cache.Applied()).To(Equal([]interface{}{"v1"}))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+
+		It("should roll back to the previous configuration when one of them fails to apply", func() {
+			recorder := &orderRecorder{}
+			database := newMockReconfigurableService("database", true, recorder)
+			queue := newMockReconfigurableService("queue", false, recorder)
+			cache := newMockReconfigurableService("cache", true, recorder)
+			engineStarter := rscsrv.NewServiceStarterWithOptions(options, database, queue, cache)
+			Expect(engineStarter.Start()).To(Succeed())
+
+			database.setConfiguration("v2")
+			queue.setConfiguration("v2")
+			cache.setConfiguration("v2")
+			cache.errApply = errors.New("apply error")
+			err := engineStarter.Reload()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("2 errors occurred: cache: apply configuration: apply error"))
+
+			cache.errApply = nil
+			Expect(database.Applied()).To(Equal([]interface{}{"v1", "v2", "v1"}))
+			Expect(queue.Applied()).To(Equal([]interface{}{"v1", "v2", "v1"}))
+			Expect(cache.Applied()).To(Equal([]interface{}{"v1"}))
+			Expect(recorder.Events()).To(Equal([]string{
+				"start database",
+				"start queue",
+				"start cache",
+				"stop queue",
+				"start queue",
+				"stop queue",
+				"start queue",
+			}))
+			Expect(stateOf(engineStarter, "queue")).To(Equal(rscsrv.StateRunning))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+	})
+})
@@ -3,6 +3,7 @@ package rscsrv
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 )
@@ -23,8 +24,66 @@ var (
 	// ErrStartCancelled is the error returned the start process is cancelled
 	// by a `Stop` call before it gets finished.
 	ErrStartCancelled = errors.New("start cancelled by a stop")
+
+	// ErrNotRetryable is the error returned when a try fails with an error
+	// that should not be retried (see `Permanent` and
+	// `StartRetrierOptions.RetryIf`).
+	ErrNotRetryable = errors.New("not retryable")
 )
 
+// RetrierError is the error returned when the `StartRetrier` gives up. It
+// matches, with `errors.Is`, both the reason (`ErrMaxTriesExceeded`,
+// `ErrStartTimeout` or `ErrNotRetryable`) and the error of the last try.
+type RetrierError struct {
+	// Reason is why the `StartRetrier` gave up.
+	Reason error
+	// Err is the error of the last try.
+	Err error
+}
+
+func (err *RetrierError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Reason, err.Err)
+}
+
+// Is reports whether target is the reason.
+func (err *RetrierError) Is(target error) bool {
+	return err.Reason == target
+}
+
+// Unwrap returns the error of the last try.
+func (err *RetrierError) Unwrap() error {
+	return err.Err
+}
+
+// permanentError marks an error that should not be retried.
+type permanentError struct {
+	err error
+}
+
+func (err *permanentError) Error() string {
+	return err.err.Error()
+}
+
+func (err *permanentError) Unwrap() error {
+	return err.err
+}
+
+// Permanent wraps err so the `StartRetrier` does not retry it and fails
+// right away. The original error can still be retrieved with `errors.Is` and
+// `errors.As`.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent returns true if err was wrapped by `Permanent`.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
 type StartRetrierReporter interface {
 	// ReportRetrier is called whenever a service is started or not. If the
 	// service is successfully started, err will be nil, otherwise not.
@@ -54,14 +113,39 @@ type StartRetrierOptions struct {
 
 	// DelayBetweenTries is the time the `StartRetrier` will wait between tries.
 	// If the duration is 0, the `StartRetrier` will use 5 second as a default
-	// value.
+	// value. It is ignored when `Backoff` is set.
 	DelayBetweenTries time.Duration
 
+	// Backoff decides how long the `StartRetrier` waits between tries. If nil,
+	// a `ConstantBackoff` of `DelayBetweenTries` will be used.
+	Backoff BackoffStrategy
+
+	// AttemptTimeout limits the duration of each attempt. It is only honoured
+	// by services that are `StartableWithContext`. 0 means no limit.
+	AttemptTimeout time.Duration
+
+	// Background makes `Start` return right away, while the retrier keeps
+	// trying to start the service in background. The `ServiceStarter` goes on
+	// with the other services and keeps this one `StateStartingInBackground`
+	// until it is started or the retrier gives up, which is reported as a
+	// `PhaseStart` event.
+	//
+	// The services that depend on this one wait for it to be started and are
+	// not started if the retrier gives up. `Ready` tells when it is
+	// available.
+	Background bool
+
+	// RetryIf decides whether a failed try should be retried. If nil, all
+	// errors, but the ones wrapped by `Permanent`, are retried.
+	RetryIf func(err error) bool
+
 	// Timeout configures for how long the system should be trying to start
 	// a service before gives it up.
 	Timeout time.Duration
 
 	// Reporter configures a receiver for all start errors that might happen.
+	// If it is also a `ServiceStarterEventReporter`, it receives a
+	// `LifecycleEvent` for each attempt.
 	Reporter StartRetrierReporter
 }
 
@@ -71,35 +155,38 @@ type StartRetrier struct {
 	starting      bool
 	startingM     sync.Mutex
 	startingDone  chan bool
-	ctx           context.Context
 	ctxCancelFunc context.CancelFunc
 	options       StartRetrierOptions
 	Try           int
+
+	// ready is closed when the service is started.
+	ready       chan struct{}
+	readyClosed bool
+	// background receives the result of the start in background.
+	background chan error
 }
 
 // NewStartRetrier configures
 func NewStartRetrier(service Service, options StartRetrierOptions) Service {
 	if options.DelayBetweenTries == 0 {
-		options.DelayBetweenTries = 5 * time.Second
+		options.DelayBetweenTries = DefaultBackoffDelay
+	}
+	if options.Backoff == nil {
+		options.Backoff = ConstantBackoff{options.DelayBetweenTries}
 	}
 	if options.Reporter == nil {
 		options.Reporter = DefaultColorStarterReporter
 	}
-	ctx, cancelFunc := context.WithCancel(context.Background())
 
-	// Initialize the retrier...
 	retrier := &StartRetrier{
-		Service:       service,
-		options:       options,
-		ctx:           ctx,
-		ctxCancelFunc: cancelFunc,
+		Service: service,
+		options: options,
 	}
 
-	// Check if the service is configurable...
-	configurable, isConfigurable := service.(Configurable)
-	if isConfigurable {
-		// If configurable, we must return an anonymous struct with a
-		// `Configurable` implemented....
+	// A configurable service is still `Configurable` once wrapped. The other
+	// abstractions of the service are found by the `ServiceStarter` through
+	// `Unwrap`.
+	if configurable, ok := service.(Configurable); ok {
 		return struct {
 			*StartRetrier
 			Configurable
@@ -108,11 +195,14 @@ func NewStartRetrier(service Service, options StartRetrierOptions) Service {
 			configurable,
 		}
 	}
-
-	// Otherwise, just return the retrier
 	return retrier
 }
 
+// Unwrap returns the service being retried.
+func (retrier *StartRetrier) Unwrap() Service {
+	return retrier.Service
+}
+
 // Retrier creates a 2nd order function to conveniently wrap `Service`s.
 func Retrier(options StartRetrierOptions) func(Service) Service {
 	return func(s Service) Service {
@@ -144,90 +234,264 @@ func (retrier *StartRetrier) setStarting(starting bool) {
 	retrier.startingM.Unlock()
 }
 
+// startFunc returns how the service is started, or nil if it is not
+// startable.
+func (retrier *StartRetrier) startFunc() func(ctx context.Context) error {
+	switch startable := findStartable(retrier.Service).(type) {
+	case StartableWithContext:
+		return startable.StartWithContext
+	case Startable:
+		return func(context.Context) error {
+			return startable.Start()
+		}
+	}
+	return nil
+}
+
 // Start starts the provided service. If it fails, the retrier will try it again
 // according to the options provided.
 func (retrier *StartRetrier) Start() error {
-	startable, ok := retrier.Service.(Startable)
-	if !ok { // No need to do anything...
+	return retrier.StartWithContext(context.Background())
+}
+
+// StartWithContext starts the provided service, trying it again according to
+// the options provided. The context of each attempt is cancelled when ctx is
+// done, when the retrier is stopped or when the `AttemptTimeout` is reached.
+//
+// With the `Background` option, it returns right away and the tries go on,
+// keeping the values of ctx, until the service is started, the retrier gives
+// up or it is stopped.
+func (retrier *StartRetrier) StartWithContext(ctx context.Context) error {
+	start := retrier.startFunc()
+	if start == nil { // No need to do anything...
+		retrier.setReady()
 		return nil
 	}
 
-	retrier.startingDone = make(chan bool)
-	defer func() {
-		retrier.setStarting(false)
-		close(retrier.startingDone)
+	var background chan error
+	if retrier.options.Background {
+		background = make(chan error, 1)
+		// Tries go on after the start of the other services.
+		ctx = detachedContext{ctx}
+	}
+
+	startCtx, cancelFunc := context.WithCancel(ctx)
+	startingDone := make(chan bool)
+	retrier.startingM.Lock()
+	retrier.ctxCancelFunc = cancelFunc
+	retrier.startingDone = startingDone
+	retrier.starting = true
+	retrier.background = background
+	retrier.resetReady()
+	retrier.startingM.Unlock()
+
+	retry := func() error {
+		defer func() {
+			cancelFunc()
+			retrier.setStarting(false)
+			close(startingDone)
+		}()
+		err := retrier.retry(ctx, startCtx, start)
+		if err == nil {
+			retrier.setReady()
+		}
+		return err
+	}
+	if background == nil {
+		return retry()
+	}
+	go func() {
+		background <- retry()
 	}()
+	return nil
+}
 
+// retry tries to start the service until it succeeds or the retrier gives
+// up. startCtx is ctx cancelled by `Stop`.
+func (retrier *StartRetrier) retry(ctx, startCtx context.Context, start func(ctx context.Context) error) error {
 	startedAt := time.Now()
 
 	retrier.Try = 0
-	retrier.setStarting(true)
+
+	var delay time.Duration
 
 	for retrier.getStarting() {
-		err := func() (err error) {
-			defer func() {
-				r := recover()
-				if r == nil {
-					return
-				}
-				if eee, ok := r.(error); ok {
-					err = eee
-				} else {
-					err = ErrUnknownPanic
-				}
-				if retrier.options.Reporter != nil { // If we have a reporter, report the error
-					err = retrier.options.Reporter.ReportRetrier(retrier, err)
-				}
-			}()
-
-			err = startable.Start()
-			if retrier.options.Reporter != nil { // If we have a reporter, report the error
-				err = retrier.options.Reporter.ReportRetrier(retrier, err)
-			}
-			return
-		}()
+		err := retrier.attempt(startCtx, start)
 		if err == nil { // If there is no error, no need to retry anything. Done.
 			return nil
 		}
 
+		// If the attempt was cancelled, by a stop or by ctx ...
+		if startCtx.Err() != nil {
+			return retrier.cancelled(ctx)
+		}
+
 		retrier.Try++
 
+		// If the error should not be retried ...
+		if !retrier.retryable(err) {
+			return &RetrierError{ErrNotRetryable, err}
+		}
+
 		// If there is a maximum number of tries defined and it was reached ...
 		if retrier.options.MaxTries > 0 && retrier.options.MaxTries <= retrier.Try {
-			return ErrMaxTriesExceeded
+			return &RetrierError{ErrMaxTriesExceeded, err}
 		}
 
 		// If there is a maximum number of time defined and it was reached ...
 		if retrier.options.Timeout > 0 && retrier.options.Timeout <= time.Since(startedAt) {
-			return ErrStartTimeout
+			return &RetrierError{ErrStartTimeout, err}
 		}
 
 		// Waits a little bit
+		delay = retrier.options.Backoff.Backoff(retrier.Try, delay)
 		select {
-		case <-time.After(retrier.options.DelayBetweenTries):
+		case <-time.After(delay):
 			continue
-		case <-retrier.ctx.Done():
-			return ErrStartCancelled
+		case <-startCtx.Done():
+			return retrier.cancelled(ctx)
+		}
+	}
+	return retrier.cancelled(ctx)
+}
+
+// StartingInBackground returns the channel that receives the result of the
+// last start, if it went to background.
+func (retrier *StartRetrier) StartingInBackground() <-chan error {
+	retrier.startingM.Lock()
+	defer retrier.startingM.Unlock()
+	return retrier.background
+}
+
+// Ready returns a channel that is closed when the service is started. A new
+// channel is used after each stop, or new start.
+func (retrier *StartRetrier) Ready() <-chan struct{} {
+	retrier.startingM.Lock()
+	defer retrier.startingM.Unlock()
+	if retrier.ready == nil {
+		retrier.ready = make(chan struct{})
+	}
+	return retrier.ready
+}
+
+func (retrier *StartRetrier) setReady() {
+	retrier.startingM.Lock()
+	defer retrier.startingM.Unlock()
+	if retrier.ready == nil {
+		retrier.ready = make(chan struct{})
+	}
+	if !retrier.readyClosed {
+		close(retrier.ready)
+		retrier.readyClosed = true
+	}
+}
+
+// resetReady replaces a closed ready channel. It must be called with the
+// startingM locked.
+func (retrier *StartRetrier) resetReady() {
+	if retrier.readyClosed {
+		retrier.ready = make(chan struct{})
+		retrier.readyClosed = false
+	}
+}
+
+// attempt tries to start the service once, recovering from panics, and
+// reports the result.
+func (retrier *StartRetrier) attempt(ctx context.Context, start func(ctx context.Context) error) (err error) {
+	if retrier.options.AttemptTimeout > 0 {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithTimeout(ctx, retrier.options.AttemptTimeout)
+		defer cancelFunc()
+	}
+
+	attemptStartedAt := time.Now()
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if eee, ok := r.(error); ok {
+			err = eee
+		} else {
+			err = ErrUnknownPanic
 		}
+		retrier.reportAttempt(ctx, attemptStartedAt, err)
+		if retrier.options.Reporter != nil { // If we have a reporter, report the error
+			err = retrier.options.Reporter.ReportRetrier(retrier, err)
+		}
+	}()
+
+	err = start(ctx)
+	retrier.reportAttempt(ctx, attemptStartedAt, err)
+	if retrier.options.Reporter != nil { // If we have a reporter, report the error
+		err = retrier.options.Reporter.ReportRetrier(retrier, err)
+	}
+	return
+}
+
+// cancelled returns the error of a start cancelled by a `Stop`, or by ctx.
+// The latter also matches the error of ctx.
+func (retrier *StartRetrier) cancelled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &RetrierError{ErrStartCancelled, err}
 	}
 	return ErrStartCancelled
 }
 
+// retryable returns true if the error of a try should be retried.
+func (retrier *StartRetrier) retryable(err error) bool {
+	if IsPermanent(err) {
+		return false
+	}
+	return retrier.options.RetryIf == nil || retrier.options.RetryIf(err)
+}
+
+// reportAttempt reports the attempt to the `Reporter`, if it is a
+// `ServiceStarterEventReporter`.
+func (retrier *StartRetrier) reportAttempt(ctx context.Context, startedAt time.Time, err error) {
+	eventReporter, ok := retrier.options.Reporter.(ServiceStarterEventReporter)
+	if !ok {
+		return
+	}
+	event := newLifecycleEvent(retrier.Service, PhaseStartAttempt, startedAt, nil, err)
+	event.Attempt = retrier.Try + 1
+	eventReporter.ReportEvent(ctx, event)
+}
+
 // Stop stops the provided service. If it still starting, the starting process
-// is cancelled.
+// is cancelled, including the attempt in progress.
 func (retrier *StartRetrier) Stop() error {
-	stoppable, ok := retrier.Service.(Stoppable)
-	if !ok { // No need to do anything...
-		return nil
+	return retrier.StopWithContext(context.Background())
+}
+
+// StopWithContext stops the provided service, or cancels the starting
+// process, until ctx is done.
+func (retrier *StartRetrier) StopWithContext(ctx context.Context) error {
+	retrier.startingM.Lock()
+	starting, cancelFunc, startingDone := retrier.starting, retrier.ctxCancelFunc, retrier.startingDone
+	retrier.starting = false
+	retrier.startingM.Unlock()
+	if starting {
+		cancelFunc()
+		select {
+		case <-startingDone:
+			return nil
+		case <-ctx.Done():
+			return stopContextErr(ctx)
+		}
 	}
 
-	if retrier.getStarting() {
-		retrier.ctxCancelFunc()
-		retrier.setStarting(false)
-		<-retrier.startingDone
-		return nil
+	retrier.startingM.Lock()
+	retrier.resetReady()
+	retrier.startingM.Unlock()
+
+	switch stoppable := findStoppable(retrier.Service).(type) {
+	case StoppableWithContext:
+		return stoppable.StopWithContext(ctx)
+	case Stoppable:
+		return stopWithDeadline(ctx, stoppable)
 	}
-	return stoppable.Stop()
+	return nil // No need to do anything...
 }
 
 // Restart restarts the provided service.
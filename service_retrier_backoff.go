@@ -0,0 +1,136 @@
+package rscsrv
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// DefaultBackoffDelay is the delay between tries used when neither
+// `StartRetrierOptions.Backoff` nor `StartRetrierOptions.DelayBetweenTries`
+// are set.
+const DefaultBackoffDelay = 5 * time.Second
+
+// BackoffStrategy is the abstraction that decides how long the
+// `StartRetrier` waits before trying to start a service again.
+//
+// Implementations must be safe for concurrent use, since the same strategy
+// can be shared by many retriers (eg: by `Retriers`).
+type BackoffStrategy interface {
+	// Backoff returns the delay before the next try. try is the number of
+	// failed tries so far, starting at 1, and previous is the delay returned
+	// for the previous try (0 for the first one).
+	Backoff(try int, previous time.Duration) time.Duration
+}
+
+// ConstantBackoff waits the same delay between all tries.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// Backoff returns the constant delay.
+func (backoff ConstantBackoff) Backoff(try int, previous time.Duration) time.Duration {
+	return backoff.Delay
+}
+
+// LinearBackoff waits a delay that grows by `Increment` on each try:
+// Initial, Initial + Increment, Initial + 2*Increment...
+type LinearBackoff struct {
+	Initial   time.Duration
+	Increment time.Duration
+
+	// Max caps the delay. 0 means no cap.
+	Max time.Duration
+}
+
+// Backoff returns the linear delay for the try.
+func (backoff LinearBackoff) Backoff(try int, previous time.Duration) time.Duration {
+	delay := float64(backoff.Initial) + float64(backoff.Increment)*float64(try-1)
+	return capBackoff(delay, backoff.Max)
+}
+
+// ExponentialBackoff waits a delay that is multiplied by `Multiplier` on each
+// try: Initial, Initial*Multiplier, Initial*Multiplier^2...
+type ExponentialBackoff struct {
+	Initial time.Duration
+
+	// Multiplier is the growth factor of the delay. If 0, 2 will be used.
+	Multiplier float64
+
+	// Max caps the delay. 0 means no cap.
+	Max time.Duration
+}
+
+// Backoff returns the exponential delay for the try.
+func (backoff ExponentialBackoff) Backoff(try int, previous time.Duration) time.Duration {
+	return capBackoff(exponentialDelay(backoff.Initial, backoff.Multiplier, try), backoff.Max)
+}
+
+// FullJitterBackoff waits a random delay between 0 and the exponential delay
+// (Initial*2^(try-1)) capped by `Max`. Spreading the tries avoids many
+// services hitting a dependency at the same time.
+type FullJitterBackoff struct {
+	Initial time.Duration
+
+	// Max caps the delay. 0 means no cap.
+	Max time.Duration
+}
+
+// Backoff returns a random delay for the try.
+func (backoff FullJitterBackoff) Backoff(try int, previous time.Duration) time.Duration {
+	return randomBackoff(0, capBackoff(exponentialDelay(backoff.Initial, 2, try), backoff.Max))
+}
+
+// DecorrelatedJitterBackoff waits a random delay between `Initial` and three
+// times the previous delay, capped by `Max`.
+type DecorrelatedJitterBackoff struct {
+	Initial time.Duration
+
+	// Max caps the delay. 0 means no cap.
+	Max time.Duration
+}
+
+// Backoff returns a random delay based on the previous one.
+func (backoff DecorrelatedJitterBackoff) Backoff(try int, previous time.Duration) time.Duration {
+	if previous < backoff.Initial {
+		previous = backoff.Initial
+	}
+	delay := randomBackoff(backoff.Initial, capBackoff(float64(previous)*3, 0))
+	if backoff.Max > 0 && delay > backoff.Max {
+		return backoff.Max
+	}
+	return delay
+}
+
+func exponentialDelay(initial time.Duration, multiplier float64, try int) float64 {
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	return float64(initial) * math.Pow(multiplier, float64(try-1))
+}
+
+// capBackoff converts the delay to a `time.Duration`, limited by max (when
+// greater than 0) and by the greatest duration, so it does not overflow.
+func capBackoff(delay float64, max time.Duration) time.Duration {
+	if max > 0 && delay > float64(max) {
+		return max
+	}
+	if delay >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
+
+// randomBackoff returns a random delay in [min, max].
+func randomBackoff(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	if max-min == math.MaxInt64 {
+		return min + time.Duration(rand.Int63())
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)+1))
+}
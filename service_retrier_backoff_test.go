@@ -0,0 +1,112 @@
+package rscsrv_test
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// backoffRecorder is a `BackoffStrategy` that records its calls.
+type backoffRecorder struct {
+	mutex    sync.Mutex
+	tries    []int
+	previous []time.Duration
+}
+
+func (backoff *backoffRecorder) Backoff(try int, previous time.Duration) time.Duration {
+	backoff.mutex.Lock()
+	defer backoff.mutex.Unlock()
+	backoff.tries = append(backoff.tries, try)
+	backoff.previous = append(backoff.previous, previous)
+	return time.Duration(try) * time.Millisecond
+}
+
+// backoffSequence returns the delays of the first tries.
+func backoffSequence(backoff rscsrv.BackoffStrategy, tries int) []time.Duration {
+	delays := make([]time.Duration, tries)
+	var previous time.Duration
+	for i := range delays {
+		previous = backoff.Backoff(i+1, previous)
+		delays[i] = previous
+	}
+	return delays
+}
+
+var _ = Describe("BackoffStrategy", func() {
+	It("should wait the same delay", func() {
+		Expect(backoffSequence(rscsrv.ConstantBackoff{Delay: time.Second}, 3)).To(Equal([]time.Duration{
+			time.Second, time.Second, time.Second,
+		}))
+	})
+
+	It("should wait a linear delay", func() {
+		Expect(backoffSequence(rscsrv.LinearBackoff{
+			Initial:   time.Second,
+			Increment: 2 * time.Second,
+			Max:       6 * time.Second,
+		}, 5)).To(Equal([]time.Duration{
+			time.Second, 3 * time.Second, 5 * time.Second, 6 * time.Second, 6 * time.Second,
+		}))
+	})
+
+	It("should wait an exponential delay", func() {
+		Expect(backoffSequence(rscsrv.ExponentialBackoff{
+			Initial: time.Second,
+			Max:     10 * time.Second,
+		}, 6)).To(Equal([]time.Duration{
+			time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second,
+		}))
+		Expect(backoffSequence(rscsrv.ExponentialBackoff{
+			Initial:    time.Second,
+			Multiplier: 3,
+		}, 3)).To(Equal([]time.Duration{
+			time.Second, 3 * time.Second, 9 * time.Second,
+		}))
+	})
+
+	It("should not overflow the exponential delay", func() {
+		backoff := rscsrv.ExponentialBackoff{Initial: time.Second}
+		Expect(backoff.Backoff(1000, 0)).To(BeNumerically(">", 0))
+	})
+
+	It("should wait a random delay up to the exponential delay", func() {
+		backoff := rscsrv.FullJitterBackoff{
+			Initial: time.Second,
+			Max:     5 * time.Second,
+		}
+		for i := 0; i < 100; i++ {
+			Expect(backoff.Backoff(1, 0)).To(BeNumerically("<=", time.Second))
+			Expect(backoff.Backoff(3, 0)).To(BeNumerically("<=", 4*time.Second))
+			Expect(backoff.Backoff(10, 0)).To(BeNumerically("<=", 5*time.Second))
+		}
+	})
+
+	It("should wait a random delay based on the previous one", func() {
+		backoff := rscsrv.DecorrelatedJitterBackoff{
+			Initial: time.Second,
+			Max:     10 * time.Second,
+		}
+		for i := 0; i < 100; i++ {
+			Expect(backoff.Backoff(1, 0)).To(And(BeNumerically(">=", time.Second), BeNumerically("<=", 3*time.Second)))
+			Expect(backoff.Backoff(2, 2*time.Second)).To(And(BeNumerically(">=", time.Second), BeNumerically("<=", 6*time.Second)))
+			Expect(backoff.Backoff(3, 8*time.Second)).To(And(BeNumerically(">=", time.Second), BeNumerically("<=", 10*time.Second)))
+		}
+	})
+
+	It("should be used by the StartRetrier between tries", func() {
+		backoff := &backoffRecorder{}
+		serviceStarter := rscsrv.NewServiceStarter(&rscsrv.NopStarterReporter{},
+			rscsrv.NewStartRetrier(&retrierMockService{successAt: 4}, rscsrv.StartRetrierOptions{
+				Reporter: &retrierMockReporter{},
+				Backoff:  backoff,
+			}),
+		)
+		Expect(serviceStarter.Start()).To(Succeed())
+		Expect(backoff.tries).To(Equal([]int{1, 2, 3}))
+		Expect(backoff.previous).To(Equal([]time.Duration{0, time.Millisecond, 2 * time.Millisecond}))
+	})
+})
@@ -1,6 +1,7 @@
 package rscsrv_test
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -60,6 +61,56 @@ func (service *retrierMockService) Stop() error {
 	return nil
 }
 
+// readyNotifier is implemented by the value returned by `NewStartRetrier`,
+// which is not a `*StartRetrier` when the service is `Configurable`.
+type readyNotifier interface {
+	Ready() <-chan struct{}
+}
+
+type retrierErrorService struct {
+	err        error
+	startCount int
+}
+
+func (service *retrierErrorService) Name() string {
+	return "retrierErrorService"
+}
+
+func (service *retrierErrorService) Start() error {
+	service.startCount++
+	return service.err
+}
+
+func (service *retrierErrorService) Stop() error {
+	return nil
+}
+
+// retrierContextService blocks each start, until ctx is done, but the one at
+// successAt.
+type retrierContextService struct {
+	startCount int
+	successAt  int
+	errs       []error
+}
+
+func (service *retrierContextService) Name() string {
+	return "retrierContextService"
+}
+
+func (service *retrierContextService) StartWithContext(ctx context.Context) error {
+	service.startCount++
+	if service.successAt == service.startCount {
+		return nil
+	}
+	<-ctx.Done()
+	service.errs = append(service.errs, ctx.Err())
+	return ctx.Err()
+}
+
+func (service *retrierContextService) Stop() error {
+	return nil
+}
+
 type retrierMockPanicService struct {
 	startDelay  time.Duration
 	startCount  int
@@ -152,7 +203,7 @@ var _ = Describe("StartRetrier", func() {
 			time.Sleep(time.Millisecond * 150)
 			Expect(retrier.(rscsrv.Stoppable).Stop()).To(Succeed())
 		}()
-		Expect(engineStarter.Start()).To(Equal(rscsrv.ErrStartCancelled))
+		Expect(errors.Is(engineStarter.Start(), rscsrv.ErrStartCancelled)).To(BeTrue())
 		Expect(service.startCount).To(Equal(2))
 		Expect(reporter.count).To(Equal(2))
 	})
@@ -182,7 +233,7 @@ var _ = Describe("StartRetrier", func() {
 			time.Sleep(time.Millisecond * 150)
 			Expect(retrier.(rscsrv.Stoppable).Stop()).To(Succeed())
 		}()
-		Expect(engineStarter.Start()).To(Equal(rscsrv.ErrStartCancelled))
+		Expect(errors.Is(engineStarter.Start(), rscsrv.ErrStartCancelled)).To(BeTrue())
 		Expect(service.startCount).To(Equal(2))
 	})
 
@@ -290,11 +341,73 @@ var _ = Describe("StartRetrier", func() {
 				}),
 			)
 			err := engineStarter.Start()
-			Expect(err).To(Equal(rscsrv.ErrMaxTriesExceeded))
+			Expect(errors.Is(err, rscsrv.ErrMaxTriesExceeded)).To(BeTrue())
+			Expect(err).To(MatchError("retrierMockService: start: too many tries: failed to start"))
 			Expect(service.startCount).To(Equal(5))
 		})
 	})
 
+	Context("Retry predicate", func() {
+		errPassword := errors.New("invalid password")
+		errConnection := errors.New("connection refused")
+
+		It("should not retry a permanent error", func() {
+			service := &retrierErrorService{err: rscsrv.Permanent(errPassword)}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+					MaxTries:          5,
+					DelayBetweenTries: time.Millisecond,
+					Reporter:          &retrierMockReporter{},
+				}),
+			)
+			err := engineStarter.Start()
+			Expect(errors.Is(err, rscsrv.ErrNotRetryable)).To(BeTrue())
+			Expect(errors.Is(err, errPassword)).To(BeTrue())
+			Expect(rscsrv.IsPermanent(err)).To(BeTrue())
+			Expect(service.startCount).To(Equal(1))
+		})
+
+		It("should only retry the errors accepted by RetryIf", func() {
+			service := &retrierErrorService{err: errPassword}
+			retrier := rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+				MaxTries:          5,
+				DelayBetweenTries: time.Millisecond,
+				Reporter:          &retrierMockReporter{},
+				RetryIf: func(err error) bool {
+					return errors.Is(err, errConnection)
+				},
+			})
+			err := retrier.(rscsrv.Startable).Start()
+			Expect(errors.Is(err, rscsrv.ErrNotRetryable)).To(BeTrue())
+			Expect(errors.Is(err, errPassword)).To(BeTrue())
+			Expect(service.startCount).To(Equal(1))
+
+			service.err = errConnection
+			err = retrier.(rscsrv.Startable).Start()
+			Expect(errors.Is(err, rscsrv.ErrMaxTriesExceeded)).To(BeTrue())
+			Expect(errors.Is(err, errConnection)).To(BeTrue())
+			Expect(service.startCount).To(Equal(6))
+		})
+
+		It("should wrap the last error when the time is over", func() {
+			service := &retrierErrorService{err: errConnection}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+					Timeout:           time.Millisecond * 20,
+					DelayBetweenTries: time.Millisecond * 5,
+					Reporter:          &retrierMockReporter{},
+				}),
+			)
+			err := engineStarter.Start()
+			Expect(errors.Is(err, rscsrv.ErrStartTimeout)).To(BeTrue())
+			var retrierErr *rscsrv.RetrierError
+			Expect(errors.As(err, &retrierErr)).To(BeTrue())
+			Expect(retrierErr.Err).To(Equal(errConnection))
+		})
+	})
+
 	Context("Timeout", func() {
 		It("should start a service right on time", func() {
 			// The retrier have timeout of 0.5 seconds.
@@ -361,7 +474,7 @@ var _ = Describe("StartRetrier", func() {
 					Reporter:          &retrierMockReporter{},
 				}),
 			)
-			Expect(engineStarter.Start()).To(Equal(rscsrv.ErrStartTimeout))
+			Expect(errors.Is(engineStarter.Start(), rscsrv.ErrStartTimeout)).To(BeTrue())
 			Expect(service.startCount).To(Equal(2))
 		})
 	})
@@ -418,4 +531,209 @@ var _ = Describe("StartRetrier", func() {
 			Expect(service.startCount).To(Equal(1))
 		}, 0.5)
 	})
+
+	Context("Context", func() {
+		It("should start a service that is only StartableWithContext", func() {
+			service := &retrierContextService{successAt: 1}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+					Reporter: &retrierMockReporter{},
+				}),
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(service.startCount).To(Equal(1))
+		})
+
+		It("should cancel the attempt after the attempt timeout", func() {
+			service := &retrierContextService{successAt: 3}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+					AttemptTimeout:    time.Millisecond * 10,
+					DelayBetweenTries: time.Millisecond,
+					Reporter:          &retrierMockReporter{},
+				}),
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(service.startCount).To(Equal(3))
+			Expect(service.errs).To(Equal([]error{context.DeadlineExceeded, context.DeadlineExceeded}))
+		})
+
+		It("should cancel the attempt in progress when stopped", func(done Done) {
+			service := &retrierContextService{}
+			retrier := rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+				Reporter: &retrierMockReporter{},
+			})
+
+			go func() {
+				time.Sleep(time.Millisecond * 50)
+				Expect(retrier.(rscsrv.Stoppable).Stop()).To(Succeed())
+				close(done)
+			}()
+			Expect(retrier.(rscsrv.StartableWithContext).StartWithContext(context.Background())).To(Equal(rscsrv.ErrStartCancelled))
+			Expect(service.startCount).To(Equal(1))
+			Expect(service.errs).To(Equal([]error{context.Canceled}))
+		}, 0.5)
+
+		It("should cancel the attempt in progress when the start context is cancelled", func(done Done) {
+			defer close(done)
+
+			service := &retrierContextService{}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+					Reporter: &retrierMockReporter{},
+				}),
+			)
+
+			ctx, cancelFunc := context.WithCancel(context.Background())
+			time.AfterFunc(time.Millisecond*50, cancelFunc)
+			err := engineStarter.StartWithContext(ctx)
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			Expect(service.startCount).To(Equal(1))
+			Expect(engineStarter.Snapshot().Services[0].State).To(Equal(rscsrv.StateStopped))
+		}, 0.5)
+	})
+
+	Context("Background", func() {
+		It("should keep starting the service in background", func(done Done) {
+			defer close(done)
+
+			service := &retrierMockService{successAt: 3}
+			retrier := rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+				DelayBetweenTries: time.Millisecond * 50,
+				Reporter:          &retrierMockReporter{},
+				Background:        true,
+			})
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				retrier,
+				&MockDependentService{name: "api", recorder: &orderRecorder{}},
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(stateOf(engineStarter, "retrierMockService")).To(Equal(rscsrv.StateStartingInBackground))
+			Expect(stateOf(engineStarter, "api")).To(Equal(rscsrv.StateRunning))
+			Expect(engineStarter.CheckHealth(context.Background()).Healthy()).To(BeFalse())
+
+			<-retrier.(readyNotifier).Ready()
+			Expect(service.startCount).To(Equal(3))
+			Eventually(func() rscsrv.ServiceState {
+				return stateOf(engineStarter, "retrierMockService")
+			}).Should(Equal(rscsrv.StateRunning))
+			Expect(engineStarter.CheckHealth(context.Background()).Healthy()).To(BeTrue())
+
+			Expect(engineStarter.Stop(false)).To(Succeed())
+			Expect(stateOf(engineStarter, "retrierMockService")).To(Equal(rscsrv.StateStopped))
+		}, 1)
+
+		It("should report the end of the start in a block of its own", func() {
+			reporter := &callRecorder{}
+			retrier := rscsrv.NewStartRetrier(&retrierMockService{successAt: 2}, rscsrv.StartRetrierOptions{
+				DelayBetweenTries: time.Millisecond * 20,
+				Reporter:          &retrierMockReporter{},
+				Background:        true,
+			})
+			engineStarter := rscsrv.NewServiceStarter(
+				reporter,
+				retrier,
+				&MockDependentService{name: "api", recorder: &orderRecorder{}},
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			Eventually(func() rscsrv.ServiceState {
+				return stateOf(engineStarter, "retrierMockService")
+			}).Should(Equal(rscsrv.StateRunning))
+
+			// BeforeBegin and AfterStart, after the calls of the api.
+			names := reporter.names
+			Expect(names[len(names)-3:]).To(Equal([]string{"api", "retrierMockService", "retrierMockService"}))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+
+		It("should fail the service when the retrier gives up", func() {
+			service := &retrierErrorService{err: errors.New("connection refused")}
+			reporter := &eventRecorder{}
+			engineStarter := rscsrv.NewServiceStarter(
+				reporter,
+				rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+					MaxTries:          2,
+					DelayBetweenTries: time.Millisecond,
+					Reporter:          &retrierMockReporter{},
+					Background:        true,
+				}),
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			Eventually(func() rscsrv.ServiceState {
+				return stateOf(engineStarter, "retrierErrorService")
+			}).Should(Equal(rscsrv.StateFailed))
+			Expect(engineStarter.Snapshot().Services[0].LastError).To(Equal("too many tries: connection refused"))
+
+			Expect(engineStarter.Stop(false)).To(Succeed())
+			Expect(stateOf(engineStarter, "retrierErrorService")).To(Equal(rscsrv.StateFailed))
+
+			// The end of the start in background is reported.
+			event := reporter.events[len(reporter.events)-1]
+			Expect(event.Phase).To(Equal(rscsrv.PhaseStart))
+			Expect(event.Err).To(MatchError("too many tries: connection refused"))
+		})
+
+		It("should start the dependents once the service is started", func() {
+			for _, parallel := range []bool{false, true} {
+				service := &retrierMockService{successAt: 3}
+				engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{Parallel: parallel},
+					rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+						DelayBetweenTries: time.Millisecond * 20,
+						Reporter:          &retrierMockReporter{},
+						Background:        true,
+					}),
+					&MockDependentService{name: "api", dependsOn: []string{"retrierMockService"}, recorder: &orderRecorder{}},
+					&MockDependentService{name: "database", recorder: &orderRecorder{}},
+				)
+				Expect(engineStarter.Start()).To(Succeed())
+				Expect(service.startCount).To(Equal(3))
+				Expect(stateOf(engineStarter, "retrierMockService")).To(Equal(rscsrv.StateRunning))
+				Expect(stateOf(engineStarter, "api")).To(Equal(rscsrv.StateRunning))
+				Expect(engineStarter.Stop(false)).To(Succeed())
+			}
+		})
+
+		It("should not start the dependents when the retrier gives up", func() {
+			for _, parallel := range []bool{false, true} {
+				engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{Parallel: parallel},
+					rscsrv.NewStartRetrier(&retrierErrorService{err: errors.New("connection refused")}, rscsrv.StartRetrierOptions{
+						MaxTries:          2,
+						DelayBetweenTries: time.Millisecond,
+						Reporter:          &retrierMockReporter{},
+						Background:        true,
+					}),
+					&MockDependentService{name: "api", dependsOn: []string{"retrierErrorService"}, recorder: &orderRecorder{}},
+				)
+				err := engineStarter.Start()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("retrierErrorService"))
+				Expect(err.Error()).To(ContainSubstring("connection refused"))
+				Expect(stateOf(engineStarter, "retrierErrorService")).To(Equal(rscsrv.StateFailed))
+				Expect(stateOf(engineStarter, "api")).To(Equal(rscsrv.StatePending))
+				Expect(engineStarter.Stop(false)).To(Succeed())
+			}
+		})
+
+		It("should cancel the start in background when stopped", func(done Done) {
+			defer close(done)
+
+			service := &retrierContextService{}
+			retrier := rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+				Reporter:   &retrierMockReporter{},
+				Background: true,
+			})
+			engineStarter := rscsrv.NewServiceStarter(&rscsrv.NopStarterReporter{}, retrier)
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(stateOf(engineStarter, "retrierContextService")).To(Equal(rscsrv.StateStartingInBackground))
+
+			Expect(engineStarter.Stop(false)).To(Succeed())
+			Expect(stateOf(engineStarter, "retrierContextService")).To(Equal(rscsrv.StateStopped))
+			Expect(service.startCount).To(BeNumerically("<=", 1))
+			Consistently(retrier.(readyNotifier).Ready(), time.Millisecond*50).ShouldNot(BeClosed())
+		}, 1)
+	})
 })
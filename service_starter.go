@@ -2,7 +2,9 @@ package rscsrv
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"time"
 )
 
 // ServiceStarter is an abtraction for service starter which is responsible
@@ -13,20 +15,134 @@ import (
 // `NewServiceStarter`, `DefaultServiceStarter`
 type ServiceStarter interface {
 	Start() error
+	// StartWithContext starts the services with a context derived from ctx,
+	// which is passed down to the services.
+	StartWithContext(ctx context.Context) error
 	Stop(keepGoing bool) error
+	StopWithContext(ctx context.Context, keepGoing bool) error
 	Wait()
+
+	// Services returns the names of the services, in the order they were
+	// provided.
+	Services() []string
+	// State returns the current state of the service with the given name. If
+	// there is no such service, ok will be false.
+	State(name string) (state ServiceState, ok bool)
+	// Snapshot returns the current state of all services.
+	Snapshot() Snapshot
+	// CheckHealth checks the health of all services.
+	CheckHealth(ctx context.Context) HealthReport
+	// Reload loads the configuration of the running services again, applying
+	// it to the ones whose configuration changed.
+	Reload() error
+	// Fatal returns a channel that receives the error that made the
+	// `ServiceStarter` shut down all services by itself, for instance when
+	// a `Runnable` service exceeds its restart budget.
+	Fatal() <-chan error
+}
+
+// ServiceStarterOptions defines the options for the `ServiceStarter`.
+type ServiceStarterOptions struct {
+	// Reporter receives the lifecycle callbacks of every service. If nil, a
+	// `NopStarterReporter` will be used.
+	//
+	// The reporter calls are serialized, so the reporter does not need to be
+	// safe for concurrent use.
+	Reporter ServiceStarterReporter
+
+	// Parallel makes the `ServiceStarter` start, concurrently, every service
+	// whose dependencies are already started. When a service fails, the
+	// context shared by all starts is cancelled and the services already
+	// started are stopped before `Start` returns.
+	Parallel bool
+
+	// ParallelStop makes the `ServiceStarter` stop, concurrently, every
+	// service that has no running dependents.
+	ParallelStop bool
+
+	// StopTimeout is the deadline for the whole shutdown. When it is
+	// reached, the services not stopped yet are left behind and
+	// `ErrStopTimeout` is returned. 0 means no deadline.
+	StopTimeout time.Duration
+
+	// ServiceStopTimeout is the default deadline for stopping each service.
+	// `StoppableWithContext` services have their context cancelled, other
+	// services are abandoned and `ErrStopTimeout` is reported for them.
+	// 0 means no deadline.
+	ServiceStopTimeout time.Duration
+
+	// ServiceStopTimeouts overrides `ServiceStopTimeout` for the services
+	// with the given names.
+	ServiceStopTimeouts map[string]time.Duration
+
+	// HealthCheckTimeout is the deadline for each `HealthChecker` to finish.
+	// If the duration is 0, 5 seconds will be used.
+	HealthCheckTimeout time.Duration
+
+	// HealthCheckCacheTTL is for how long the result of a `HealthChecker` is
+	// reused before checking the service again. 0 means results are not
+	// cached.
+	HealthCheckCacheTTL time.Duration
+
+	// Supervisor defines how `Runnable` services are restarted when they
+	// stop running. Services can override it by implementing `Supervised`.
+	Supervisor SupervisorOptions
+
+	// Strategy defines which services are restarted together when a
+	// `Runnable` service stops running. If empty, `OneForOne` will be used.
+	Strategy SupervisionStrategy
+
+	// RestartOnReload makes `Reload` restart the services whose
+	// configuration changed but cannot be applied while running (see
+	// `Reconfigurable`). Otherwise, the new configuration is ignored until
+	// the next start.
+	RestartOnReload bool
+
+	// TransactionalConfiguration makes the `ServiceStarter` load, and
+	// validate, the configuration of all services before starting any of
+	// them. On `Reload`, new configurations are applied only if all of them
+	// are loaded, and the previous ones are applied back if any fails.
+	TransactionalConfiguration bool
+
+	// StartTimeout is the deadline for starting all services. If zero, there
+	// is no deadline.
+	StartTimeout time.Duration
 }
 
 type serviceStarter struct {
 	ctx        context.Context
 	cancelFunc context.CancelFunc
+	// detachedCtx keeps the values of the start context for the services
+	// started outside of `Start` (eg: `Run` and restarts).
+	detachedCtx context.Context
 
 	chMutex     sync.RWMutex
 	startDoneCh chan bool
 	stopDoneCh  chan bool
 	services    []Service
-	started     []Service
-	reporter    ServiceStarterReporter
+	statuses    []*serviceStatus
+	nodes       []*serviceNode
+	started     []*serviceNode
+	reporter    *syncStarterReporter
+	options     ServiceStarterOptions
+	health      healthCache
+
+	// stopMutex prevents concurrent `Stop`s and `Reload`s. It also guards
+	// starting and detachedCtx.
+	stopMutex sync.Mutex
+	// starting is true while `Start` is in progress.
+	starting bool
+	// restartMutex prevents the supervisor from restarting services during a
+	// `Reload`. It is not taken by `Stop`, which stops the supervisor first.
+	restartMutex sync.Mutex
+
+	supervisorCtx    context.Context
+	supervisorCancel context.CancelFunc
+	supervisorDone   chan struct{}
+	runExits         chan runExit
+	fatalCh          chan error
+	fatalOnce        *sync.Once
+	fatalFired       bool
 }
 
 // DefaultServiceStarter returns a default ServiceStarter integrated
@@ -41,29 +157,145 @@ func QuietServiceStarter(services ...Service) ServiceStarter {
 	return NewServiceStarter(&NopStarterReporter{}, services...)
 }
 
+// ParallelServiceStarter returns a ServiceStarter that starts independent
+// services concurrently.
+//
+// See Also
+//
+// `ServiceStarterOptions.Parallel`
+func ParallelServiceStarter(reporter ServiceStarterReporter, services ...Service) ServiceStarter {
+	return NewServiceStarterWithOptions(ServiceStarterOptions{
+		Reporter: reporter,
+		Parallel: true,
+	}, services...)
+}
+
 // NewServiceStarter returns a new instace of a `ServiceStarter`.
 func NewServiceStarter(reporter ServiceStarterReporter, services ...Service) ServiceStarter {
+	return NewServiceStarterWithOptions(ServiceStarterOptions{
+		Reporter: reporter,
+	}, services...)
+}
+
+// NewServiceStarterWithOptions returns a new instance of a `ServiceStarter`
+// configured by the given options.
+func NewServiceStarterWithOptions(options ServiceStarterOptions, services ...Service) ServiceStarter {
+	if options.Reporter == nil {
+		options.Reporter = &NopStarterReporter{}
+	}
+	reporter := &syncStarterReporter{
+		reporter: options.Reporter,
+	}
+	_, breakerReporter := options.Reporter.(CircuitBreakerReporter)
+	_, stateReporter := options.Reporter.(ServiceStateReporter)
+	statuses := make([]*serviceStatus, len(services))
+	for i, srv := range services {
+		statuses[i] = newServiceStatus(srv)
+		if stateReporter {
+			srv := srv
+			statuses[i].onChange = func(state ServiceState, err error) {
+				reporter.ReportState(srv, state, err)
+			}
+		}
+		if !breakerReporter {
+			continue
+		}
+		// The breakers without a reporter report to the one of the starter.
+		if breaker, ok := findService(srv, func(srv Service) bool {
+			_, ok := srv.(*CircuitBreaker)
+			return ok
+		}).(*CircuitBreaker); ok {
+			breaker.useReporter(reporter)
+		}
+	}
 	return &serviceStarter{
-		services: services,
-		started:  make([]Service, 0, len(services)),
-		reporter: reporter,
+		services:    services,
+		statuses:    statuses,
+		started:     make([]*serviceNode, 0, len(services)),
+		reporter:    reporter,
+		options:     options,
+		detachedCtx: context.Background(),
+		fatalCh:     make(chan error, 1),
+		fatalOnce:   &sync.Once{},
 	}
 }
 
 // Start will go through all provided services trying to load and/or start them.
+//
+// When a service fails, a `ServiceError` is returned identifying the service
+// and the phase that failed. If the start is cancelled by a `Stop`, the
+// context error is returned instead.
+//
+// Services are started after all services they depend on (see `Dependent`).
+// If the dependencies cannot be resolved, a `DependencyNotFoundError` or a
+// `DependencyCycleError` is returned before any service is touched.
 func (engineStarter *serviceStarter) Start() error {
+	return engineStarter.StartWithContext(context.Background())
+}
+
+// StartWithContext works as `Start`, but the services are started with a
+// context derived from ctx. If ctx is cancelled, or the
+// `ServiceStarterOptions.StartTimeout` is reached, the start is cancelled and
+// the context error is returned.
+func (engineStarter *serviceStarter) StartWithContext(ctx context.Context) (err error) {
+	ctx = withSequence(ctx, SequenceStart)
+
 	engineStarter.chMutex.Lock()
-	engineStarter.ctx, engineStarter.cancelFunc = context.WithCancel(context.Background())
+	if engineStarter.options.StartTimeout > 0 {
+		engineStarter.ctx, engineStarter.cancelFunc = context.WithTimeout(ctx, engineStarter.options.StartTimeout)
+	} else {
+		engineStarter.ctx, engineStarter.cancelFunc = context.WithCancel(ctx)
+	}
 	engineStarter.startDoneCh = make(chan bool)
 	engineStarter.stopDoneCh = make(chan bool)
+	if engineStarter.fatalFired {
+		// Starting again after shutting down by itself.
+		engineStarter.fatalCh = make(chan error, 1)
+		engineStarter.fatalOnce = &sync.Once{}
+		engineStarter.fatalFired = false
+	}
 	engineStarter.chMutex.Unlock()
+
+	// A `Reload` cannot run along with the start.
+	engineStarter.stopMutex.Lock()
+	engineStarter.starting = true
+	engineStarter.detachedCtx = detachedContext{ctx}
+	engineStarter.stopMutex.Unlock()
 	defer func() {
 		close(engineStarter.startDoneCh)
 		engineStarter.cancelFunc()
+
+		engineStarter.stopMutex.Lock()
+		engineStarter.starting = false
+		engineStarter.stopMutex.Unlock()
+	}()
+
+	engineStarter.reporter.BeginSequence(ctx, SequenceStart)
+	defer func() {
+		engineStarter.reporter.EndSequence(ctx, SequenceStart, err)
 	}()
 
+	nodes, err := buildServiceGraph(engineStarter.services)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		node.status = engineStarter.statuses[node.index]
+	}
+	engineStarter.nodes = nodes
+	if engineStarter.options.TransactionalConfiguration {
+		if err := engineStarter.preloadConfiguration(engineStarter.ctx, nodes); err != nil {
+			return err
+		}
+	}
+	engineStarter.startSupervisor()
+
+	if engineStarter.options.Parallel {
+		return engineStarter.startParallel(nodes)
+	}
+
 	// Iterate through all services
-	for _, srv := range engineStarter.services {
+	for _, node := range nodes {
 		engineStarter.chMutex.RLock()
 		// Ensure the context is not cancelled:
 		select {
@@ -76,98 +308,629 @@ func (engineStarter *serviceStarter) Start() error {
 		}
 		engineStarter.chMutex.RUnlock()
 
-		engineStarter.reporter.BeforeBegin(srv)
+		started, err := engineStarter.startService(engineStarter.ctx, node)
+		if err != nil {
+			return err
+		}
+		if started {
+			// Stop walks this list backwards, so resources get unallocated in
+			// the reverse order they were started.
+			engineStarter.started = append(engineStarter.started, node)
+		}
+	}
+	select {
+	case <-engineStarter.ctx.Done():
+		// Broadcast the start is done...
+		return engineStarter.ctx.Err()
+	default:
+		// Not cancelled ... everything must go on.
+	}
+	return nil
+}
+
+// nodeResult is the outcome of starting, or stopping, a service concurrently.
+type nodeResult struct {
+	node    *serviceNode
+	started bool
+	err     error
+}
 
-		// If the service is Configurable, starts loading the configuration.
-		if configurable, ok := srv.(Configurable); ok {
-			engineStarter.reporter.BeforeLoadConfiguration(configurable)
+// startParallel starts every service as soon as all of its dependencies are
+// started. The first failure cancels the starts in progress and stops the
+// services already started.
+func (engineStarter *serviceStarter) startParallel(nodes []*serviceNode) error {
+	ctx, cancelFunc := context.WithCancel(engineStarter.ctx)
+	defer cancelFunc()
 
-			// Loads configuration
-			conf, err := configurable.LoadConfiguration()
-			engineStarter.reporter.AfterLoadConfiguration(configurable, conf, err)
-			if err != nil {
-				return err
+	// The calls of each service are reported together.
+	engineStarter.reporter.beginParallel()
+	defer engineStarter.reporter.endParallel()
+
+	results := make(chan nodeResult)
+	running := 0
+	launch := func(node *serviceNode) {
+		running++
+		go func() {
+			started, err := engineStarter.startService(ctx, node)
+			results <- nodeResult{node, started, err}
+		}()
+	}
+
+	// Counts how many dependencies each service still waits for.
+	waiting := make(map[*serviceNode]int, len(nodes))
+	for _, node := range nodes {
+		waiting[node] = len(node.dependencies)
+		if len(node.dependencies) == 0 {
+			launch(node)
+		}
+	}
+
+	var firstErr error
+	for running > 0 {
+		result := <-results
+		running--
+		if result.started {
+			engineStarter.started = append(engineStarter.started, result.node)
+		}
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+				cancelFunc()
 			}
-			engineStarter.reporter.BeforeApplyConfiguration(configurable)
+			continue
+		}
+		if ctx.Err() != nil {
+			continue
+		}
+		for _, dependent := range result.node.dependents {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				launch(dependent)
+			}
+		}
+	}
+
+	// Cancelled by a `Stop`, which will take care of the started services.
+	if err := engineStarter.ctx.Err(); err != nil {
+		return err
+	}
+	if firstErr != nil {
+		ctx, cancelFunc := engineStarter.stopContext(context.Background())
+		defer cancelFunc()
+		engineStarter.stopStarted(ctx, true)
+		return firstErr
+	}
+	return nil
+}
+
+// startService loads and applies the configuration of the service, if it is
+// `Configurable`, and starts it. started reports whether the service is
+// running, so `Stop` must take care of it.
+func (engineStarter *serviceStarter) startService(ctx context.Context, node *serviceNode) (started bool, err error) {
+	if err := waitBackgroundDependencies(ctx, node); err != nil {
+		return false, err
+	}
+
+	srv := node.service
+	engineStarter.reporter.BeforeBegin(srv)
+	defer engineStarter.reporter.endService(srv)
 
-			// Applies the configuration to the service.
-			err = configurable.ApplyConfiguration(conf)
-			engineStarter.reporter.AfterApplyConfiguration(configurable, conf, err)
+	// If the service is Configurable, starts loading the configuration.
+	if _, ok := asConfigurable(srv); ok {
+		node.status.set(StateConfiguring, nil)
+
+		conf := node.loaded
+		if !node.preloaded {
+			conf, err = engineStarter.loadConfiguration(ctx, node)
 			if err != nil {
-				return err
+				node.status.set(StateFailed, errors.Unwrap(err))
+				return false, err
 			}
 		}
+		node.loaded, node.preloaded = nil, false
 
-		var err error
-		switch startable := srv.(type) {
-		case StartableWithContext:
-			// If the service is Startable, tries to start the service.
-			engineStarter.reporter.BeforeStart(srv)
-			err = startable.StartWithContext(engineStarter.ctx)
-		case Startable:
-			// If the service is Startable, tries to start the service.
-			engineStarter.reporter.BeforeStart(srv)
-			err = startable.Start()
-		default:
-			continue
+		// Applies the configuration to the service.
+		if err := engineStarter.applyConfiguration(ctx, node, conf); err != nil {
+			node.status.set(StateFailed, err)
+			return false, newServiceError(srv, PhaseApplyConfiguration, err)
+		}
+	}
+
+	return engineStarter.startNode(ctx, node)
+}
+
+// loadConfiguration loads the configuration of the service and validates it,
+// if the service is a `ConfigurationValidator`.
+func (engineStarter *serviceStarter) loadConfiguration(ctx context.Context, node *serviceNode) (conf interface{}, err error) {
+	configurable, _ := asConfigurable(node.service)
+	engineStarter.reporter.BeforeLoadConfiguration(configurable)
+	startedAt := time.Now()
+	if configurableWithContext, ok := configurable.(ConfigurableWithContext); ok {
+		conf, err = configurableWithContext.LoadConfigurationWithContext(ctx)
+	} else {
+		conf, err = configurable.LoadConfiguration()
+	}
+	engineStarter.reporter.ReportEvent(ctx, newLifecycleEvent(node.service, PhaseLoadConfiguration, startedAt, conf, err))
+	if err != nil {
+		return nil, newServiceError(node.service, PhaseLoadConfiguration, err)
+	}
+	if validator, ok := configurable.(ConfigurationValidator); ok {
+		if err := validator.ValidateConfiguration(conf); err != nil {
+			return nil, newServiceError(node.service, PhaseValidateConfiguration, err)
 		}
+	}
+	return conf, nil
+}
 
-		engineStarter.reporter.AfterStart(srv, err)
+// applyConfiguration applies the configuration to the service and keeps it
+// for comparing on `Reload`.
+func (engineStarter *serviceStarter) applyConfiguration(ctx context.Context, node *serviceNode, conf interface{}) error {
+	configurable, _ := asConfigurable(node.service)
+	engineStarter.reporter.BeforeApplyConfiguration(configurable)
+	startedAt := time.Now()
+	err := configurable.ApplyConfiguration(conf)
+	engineStarter.reporter.ReportEvent(ctx, newLifecycleEvent(node.service, PhaseApplyConfiguration, startedAt, conf, err))
+	if err != nil {
+		return err
+	}
+	node.conf, node.configured = conf, true
+	return nil
+}
+
+// preloadConfiguration loads the configuration of all `Configurable`
+// services, before any of them is started. All failures are returned in a
+// `MultiError`.
+func (engineStarter *serviceStarter) preloadConfiguration(ctx context.Context, nodes []*serviceNode) error {
+	var errs MultiError
+	for _, node := range nodes {
+		if _, ok := asConfigurable(node.service); !ok {
+			continue
+		}
+		engineStarter.reporter.BeforeBegin(node.service)
+		node.status.set(StateConfiguring, nil)
+		conf, err := engineStarter.loadConfiguration(ctx, node)
 		if err != nil {
-			return err
+			node.status.set(StateFailed, errors.Unwrap(err))
+			errs = append(errs, err)
+			continue
 		}
-		// Prepend the service to the list of started services.
-		// The order is reverse to get the resources unallocated in the reverse order as they started.
-		engineStarter.started = append([]Service{srv}, engineStarter.started...)
+		node.status.set(StatePending, nil)
+		node.loaded, node.preloaded = conf, true
 	}
-	select {
-	case <-engineStarter.ctx.Done():
-		// Broadcast the start is done...
-		return engineStarter.ctx.Err()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// startNode starts the service, if it is `Startable` or
+// `StartableWithContext`, and runs it, if it is `Runnable`. started reports
+// whether the service is running, so `Stop` must take care of it, even if
+// there is nothing to stop but its state.
+func (engineStarter *serviceStarter) startNode(ctx context.Context, node *serviceNode) (started bool, err error) {
+	srv := node.service
+	var startedAt time.Time
+	switch startable := findStartable(srv).(type) {
+	case StartableWithContext:
+		// If the service is Startable, tries to start the service.
+		node.status.set(StateStarting, nil)
+		engineStarter.reporter.BeforeStart(srv)
+		startedAt = time.Now()
+		err = startable.StartWithContext(ctx)
+	case Startable:
+		// If the service is Startable, tries to start the service.
+		node.status.set(StateStarting, nil)
+		engineStarter.reporter.BeforeStart(srv)
+		startedAt = time.Now()
+		err = startable.Start()
 	default:
-		// Not cancelled ... everything must go on.
+		// Nothing to start, the service is ready to be used.
+		node.status.set(StateRunning, nil)
+		var runnable Runnable
+		if As(srv, &runnable) {
+			engineStarter.launchRun(node, runnable)
+		}
+		return true, nil
+	}
+
+	if err == nil {
+		if result := startingInBackground(srv); result != nil {
+			node.backgroundFailed = false
+			node.background = &backgroundStart{done: make(chan struct{})}
+			// The start is reported when it finishes.
+			node.status.set(StateStartingInBackground, nil)
+			go engineStarter.finishBackgroundStart(node, node.background, startedAt, result)
+			return true, nil
+		}
+	}
+
+	engineStarter.reporter.ReportEvent(ctx, newLifecycleEvent(srv, PhaseStart, startedAt, nil, err))
+	if err != nil {
+		// The service just gave up because the start was cancelled.
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			node.status.set(StateStopped, err)
+			return false, ctxErr
+		}
+		node.status.set(StateFailed, err)
+		return false, newServiceError(srv, PhaseStart, err)
+	}
+	node.status.set(StateRunning, nil)
+	var runnable Runnable
+	if As(srv, &runnable) {
+		engineStarter.launchRun(node, runnable)
+	}
+	return true, nil
+}
+
+// startingInBackground returns the channel that receives the result of the
+// start of srv, if it is still starting in background.
+func startingInBackground(srv Service) <-chan error {
+	var backgroundStartable BackgroundStartable
+	if !As(srv, &backgroundStartable) {
+		return nil
+	}
+	return backgroundStartable.StartingInBackground()
+}
+
+// waitBackgroundDependencies waits for the dependencies of the service that
+// are starting in background. If any of them fails, its error is returned.
+func waitBackgroundDependencies(ctx context.Context, node *serviceNode) error {
+	for _, dependency := range node.dependencies {
+		background := dependency.background
+		if background == nil {
+			continue
+		}
+		select {
+		case <-background.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if background.err != nil {
+			return newServiceError(dependency.service, PhaseStart, background.err)
+		}
 	}
 	return nil
 }
 
-// Stop will stop all started "startable" services.
+// finishBackgroundStart waits for the start in background of the service and
+// moves it to running, or failed. Services stopped meanwhile are left alone.
+// Either way, the end of the start is reported as a `PhaseStart` event and
+// the dependents of the service, waiting for it, are released.
+func (engineStarter *serviceStarter) finishBackgroundStart(node *serviceNode, background *backgroundStart, startedAt time.Time, result <-chan error) {
+	err := <-result
+	defer func() {
+		background.err = err
+		close(background.done)
+	}()
+
+	// Services cannot be stopped while leaving the background.
+	engineStarter.stopMutex.Lock()
+	defer engineStarter.stopMutex.Unlock()
+
+	srv := node.service
+	// The other services were reported in the meantime, so the result is
+	// reported in a block of its own.
+	engineStarter.reporter.BeforeBegin(srv)
+	engineStarter.reporter.ReportEvent(engineStarter.detachedCtx, newLifecycleEvent(srv, PhaseStart, startedAt, nil, err))
+	state := StateRunning
+	if err != nil {
+		state = StateFailed
+	}
+	if !node.status.setIf(StateStartingInBackground, state, err) {
+		return
+	}
+	node.backgroundFailed = err != nil
+	var runnable Runnable
+	if err == nil && As(srv, &runnable) {
+		engineStarter.launchRun(node, runnable)
+	}
+}
+
+// Stop will stop all started "startable" services, in the reverse order they
+// were started.
+//
+// If keepGoing is false, the first failure interrupts the shutdown and is
+// returned as a `ServiceError`. Otherwise, all services are stopped and every
+// failure is returned in a `MultiError`.
 func (engineStarter *serviceStarter) Stop(keepGoing bool) error {
+	return engineStarter.StopWithContext(context.Background(), keepGoing)
+}
+
+// StopWithContext will stop all started "startable" services, in the reverse
+// order they were started. If ctx is done before all services are stopped,
+// the remaining services are left behind.
+func (engineStarter *serviceStarter) StopWithContext(ctx context.Context, keepGoing bool) (err error) {
+	engineStarter.stopMutex.Lock()
+	defer engineStarter.stopMutex.Unlock()
+
 	defer func() {
 		engineStarter.chMutex.Lock()
-		close(engineStarter.stopDoneCh)
+		select {
+		case <-engineStarter.stopDoneCh:
+			// Already closed by a previous `Stop`.
+		default:
+			close(engineStarter.stopDoneCh)
+		}
 		engineStarter.chMutex.Unlock()
 	}()
 
+	ctx, cancelFunc := engineStarter.stopContext(withSequence(ctx, SequenceStop))
+	defer cancelFunc()
+
+	engineStarter.reporter.BeginSequence(ctx, SequenceStop)
+	defer func() {
+		engineStarter.reporter.EndSequence(ctx, SequenceStop, err)
+	}()
+
 	engineStarter.chMutex.RLock()
 	if engineStarter.ctx != nil {
 		engineStarter.cancelFunc()
 		engineStarter.chMutex.RUnlock()
-		<-engineStarter.startDoneCh
+		select {
+		case <-engineStarter.startDoneCh:
+		case <-ctx.Done():
+			return stopContextErr(ctx)
+		}
 	} else {
 		engineStarter.chMutex.RUnlock()
 	}
 
+	// No service gets restarted from now on.
+	engineStarter.stopSupervisor()
+
+	return engineStarter.stopStarted(ctx, keepGoing)
+}
+
+// stopContext derives the context of the whole shutdown from the given one,
+// applying the `StopTimeout` option.
+func (engineStarter *serviceStarter) stopContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if engineStarter.options.StopTimeout > 0 {
+		return context.WithTimeout(ctx, engineStarter.options.StopTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// stopContextErr translates a done stop context into the error reported.
+func stopContextErr(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrStopTimeout
+	}
+	return ctx.Err()
+}
+
+// stopStarted stops the started services, the last started first.
+func (engineStarter *serviceStarter) stopStarted(ctx context.Context, keepGoing bool) error {
+	if engineStarter.options.ParallelStop {
+		return engineStarter.stopParallel(ctx, keepGoing)
+	}
+
+	var errs MultiError
 	for len(engineStarter.started) > 0 {
-		srv := engineStarter.started[0]
-		engineStarter.reporter.BeforeBegin(srv)
+		if ctx.Err() != nil {
+			if !keepGoing {
+				return stopContextErr(ctx)
+			}
+			return append(errs, stopContextErr(ctx))
+		}
 
-		// If the service is Stoppable, tries to stop the service.
-		if stoppable, ok := srv.(Stoppable); ok {
-			engineStarter.reporter.BeforeStop(srv)
-			err := stoppable.Stop()
-			engineStarter.reporter.AfterStop(srv, err)
-			if err != nil && !keepGoing {
+		node := engineStarter.started[len(engineStarter.started)-1]
+		err := engineStarter.stopService(ctx, node)
+		if err != nil {
+			if !keepGoing {
 				return err
 			}
+			errs = append(errs, err)
 		}
 
 		// Removes the service from the list of started services.
-		engineStarter.started = engineStarter.started[1:]
+		engineStarter.started = engineStarter.started[:len(engineStarter.started)-1]
+	}
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
 
+// stopParallel stops, concurrently, every started service as soon as all of
+// its started dependents are stopped.
+func (engineStarter *serviceStarter) stopParallel(ctx context.Context, keepGoing bool) error {
+	started := make(map[*serviceNode]bool, len(engineStarter.started))
+	for _, node := range engineStarter.started {
+		started[node] = true
+	}
+
+	// Counts how many started dependents each service still waits for.
+	waiting := make(map[*serviceNode]int, len(engineStarter.started))
+	for _, node := range engineStarter.started {
+		for _, dependent := range node.dependents {
+			if started[dependent] {
+				waiting[node]++
+			}
+		}
+	}
+
+	results := make(chan nodeResult)
+	running := 0
+	launch := func(node *serviceNode) {
+		running++
+		go func() {
+			err := engineStarter.stopService(ctx, node)
+			results <- nodeResult{node: node, err: err}
+		}()
+	}
+	for i := len(engineStarter.started) - 1; i >= 0; i-- {
+		if node := engineStarter.started[i]; waiting[node] == 0 {
+			launch(node)
+		}
+	}
+
+	var errs MultiError
+	for running > 0 {
+		result := <-results
+		running--
+		if result.err != nil {
+			errs = append(errs, result.err)
+			if !keepGoing {
+				continue
+			}
+		}
+		delete(started, result.node)
+		if (len(errs) > 0 && !keepGoing) || ctx.Err() != nil {
+			continue
+		}
+		for _, dependency := range result.node.dependencies {
+			if !started[dependency] {
+				continue
+			}
+			waiting[dependency]--
+			if waiting[dependency] == 0 {
+				launch(dependency)
+			}
+		}
+	}
+
+	// Keeps the services that could not be stopped, in their start order.
+	remaining := make([]*serviceNode, 0, len(started))
+	for _, node := range engineStarter.started {
+		if started[node] {
+			remaining = append(remaining, node)
+		}
+	}
+	engineStarter.started = remaining
+
+	if len(errs) > 0 && !keepGoing {
+		return errs[0]
+	}
+	if len(remaining) > 0 && ctx.Err() != nil {
+		if !keepGoing {
+			return stopContextErr(ctx)
+		}
+		errs = append(errs, stopContextErr(ctx))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// stopService stops the service, if it is `Stoppable` or
+// `StoppableWithContext`, respecting its stop timeout.
+func (engineStarter *serviceStarter) stopService(ctx context.Context, node *serviceNode) (err error) {
+	if node.backgroundFailed {
+		// Never started.
+		return nil
+	}
+	srv := node.service
+	var startedAt time.Time
+	engineStarter.reporter.BeforeBegin(srv)
+
+	timeout := engineStarter.options.ServiceStopTimeout
+	if t, ok := engineStarter.options.ServiceStopTimeouts[srv.Name()]; ok {
+		timeout = t
+	}
+	if timeout > 0 {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithTimeout(ctx, timeout)
+		defer cancelFunc()
+	}
+
+	if err := stopRun(ctx, node); err != nil {
+		node.status.set(StateFailed, err)
+		return newServiceError(srv, PhaseRun, err)
+	}
+
+	switch stoppable := findStoppable(srv).(type) {
+	case StoppableWithContext:
+		// If the service is Stoppable, tries to stop the service.
+		node.status.set(StateStopping, nil)
+		engineStarter.reporter.BeforeStop(srv)
+		startedAt = time.Now()
+		err = stoppable.StopWithContext(ctx)
+	case Stoppable:
+		// If the service is Stoppable, tries to stop the service.
+		node.status.set(StateStopping, nil)
+		engineStarter.reporter.BeforeStop(srv)
+		startedAt = time.Now()
+		err = stopWithDeadline(ctx, stoppable)
+	default:
+		node.status.set(StateStopped, nil)
+		return nil
+	}
+	engineStarter.reporter.ReportEvent(ctx, newLifecycleEvent(srv, PhaseStop, startedAt, nil, err))
+	if err != nil {
+		node.status.set(StateFailed, err)
+		return newServiceError(srv, PhaseStop, err)
+	}
+	node.status.set(StateStopped, nil)
+	return nil
+}
+
+// stopWithDeadline calls the `Stop` of a service that cannot be cancelled. If
+// ctx is done first, the service is abandoned and `ErrStopTimeout` returned.
+func stopWithDeadline(ctx context.Context, stoppable Stoppable) error {
+	if ctx.Done() == nil {
+		return stoppable.Stop()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- stoppable.Stop()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return stopContextErr(ctx)
+	}
+}
+
 // Wait will keep waiting until the Stop be finished.
 func (engineStarter *serviceStarter) Wait() {
 	<-engineStarter.stopDoneCh
 }
+
+// Services returns the names of the services, in the order they were
+// provided.
+func (engineStarter *serviceStarter) Services() []string {
+	names := make([]string, len(engineStarter.statuses))
+	for i, status := range engineStarter.statuses {
+		names[i] = status.name
+	}
+	return names
+}
+
+// State returns the current state of the service with the given name. If
+// there is no such service, ok will be false.
+func (engineStarter *serviceStarter) State(name string) (ServiceState, bool) {
+	for _, status := range engineStarter.statuses {
+		if status.name == name {
+			return status.State(), true
+		}
+	}
+	return "", false
+}
+
+// Snapshot returns the current state of all services.
+func (engineStarter *serviceStarter) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Time:     time.Now(),
+		Services: make([]ServiceSnapshot, len(engineStarter.statuses)),
+	}
+	for i, status := range engineStarter.statuses {
+		snapshot.Services[i] = status.Snapshot()
+	}
+	return snapshot
+}
+
+// Fatal returns a channel that receives the error that made the
+// `ServiceStarter` shut down all services by itself.
+func (engineStarter *serviceStarter) Fatal() <-chan error {
+	engineStarter.chMutex.RLock()
+	defer engineStarter.chMutex.RUnlock()
+	return engineStarter.fatalCh
+}
+
+// starterReporter returns the reporter of the starter, so the signal actions
+// are reported through it.
+func (engineStarter *serviceStarter) starterReporter() ServiceStarterReporter {
+	return engineStarter.reporter
+}
@@ -1,7 +1,9 @@
 package rscsrv
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/fatih/color"
 )
@@ -9,6 +11,7 @@ import (
 var (
 	colorSuccess   = color.New(color.FgGreen)
 	colorError     = color.New(color.FgRed)
+	colorWarning   = color.New(color.FgYellow)
 	colorHighlight = color.New(color.FgWhite, color.Bold)
 )
 
@@ -16,12 +19,17 @@ var (
 var (
 	formatSuccess   = colorSuccess.SprintfFunc()
 	formatError     = colorError.SprintfFunc()
+	formatWarning   = colorWarning.SprintfFunc()
 	formatHighlight = colorHighlight.SprintfFunc()
 
 	formatBold = colorSuccess.SprintfFunc()
 )
 
-type ColorStarterReporter struct{}
+type ColorStarterReporter struct {
+	// SlowThreshold flags the phases that take longer than it. If zero, no
+	// phase is flagged.
+	SlowThreshold time.Duration
+}
 
 var DefaultColorStarterReporter = &ColorStarterReporter{}
 
@@ -50,6 +58,40 @@ func (reporter *ColorStarterReporter) printError(err error) {
 	reporter.printL1f("> [%s]", t)
 }
 
+// printResult prints the result of a phase along with its duration.
+func (reporter *ColorStarterReporter) printResult(err error, duration time.Duration) {
+	d := formatDuration(duration)
+	if reporter.SlowThreshold > 0 && duration > reporter.SlowThreshold {
+		d = formatWarning("%s, slow", d)
+	}
+	if err != nil {
+		reporter.printL1f("> [%s] (%s): %s", formatBold(formatError("Error")), d, err)
+		return
+	}
+	reporter.printL1f("> [%s] (%s)", formatBold(formatSuccess("OK")), d)
+}
+
+// formatDuration rounds the duration to be easily read.
+func formatDuration(duration time.Duration) string {
+	if duration < time.Millisecond {
+		return duration.Round(time.Microsecond).String()
+	}
+	return duration.Round(time.Millisecond).String()
+}
+
+// ReportEvent prints the result of the phase with its duration. The attempts
+// of a `StartRetrier` are printed by `ReportRetrier`.
+func (reporter *ColorStarterReporter) ReportEvent(ctx context.Context, event LifecycleEvent) {
+	switch event.Phase {
+	case PhaseStartAttempt:
+		return
+	case PhaseRun:
+		// The run returns long after the service was started.
+		reporter.printL1f("%s > Run ...", event.Name)
+	}
+	reporter.printResult(event.Err, event.Duration)
+}
+
 func (reporter *ColorStarterReporter) AfterLoadConfiguration(service Configurable, conf interface{}, err error) {
 	reporter.printError(err)
 }
@@ -86,3 +128,35 @@ func (reporter *ColorStarterReporter) ReportRetrier(retrier *StartRetrier, err e
 	}
 	return err
 }
+
+// ReportCircuitBreaker prints the transitions of a `CircuitBreaker`.
+func (reporter *ColorStarterReporter) ReportCircuitBreaker(breaker *CircuitBreaker, from, to CircuitState, err error) {
+	switch {
+	case to == CircuitOpen && err != nil:
+		reporter.printL1f("%s > Circuit [%s]: %s", breaker.Name(), formatError(string(to)), err)
+	case to == CircuitOpen:
+		reporter.printL1f("%s > Circuit [%s]", breaker.Name(), formatError(string(to)))
+	case to == CircuitHalfOpen:
+		reporter.printL1f("%s > Circuit [%s]", breaker.Name(), formatWarning(string(to)))
+	default:
+		reporter.printL1f("%s > Circuit [%s]", breaker.Name(), formatSuccess(string(to)))
+	}
+}
+
+// ReportReload prints the result of a reload.
+func (reporter *ColorStarterReporter) ReportReload(err error) {
+	fmt.Printf("%s\n", formatHighlight("Reload"))
+	reporter.printError(err)
+}
+
+// ReportSnapshot prints the state of each service.
+func (reporter *ColorStarterReporter) ReportSnapshot(snapshot Snapshot) {
+	fmt.Printf("%s\n", formatHighlight("Services"))
+	for _, service := range snapshot.Services {
+		if service.LastError != "" {
+			reporter.printL1f("%-20s %s: %s", service.Name, formatError(string(service.State)), service.LastError)
+			continue
+		}
+		reporter.printL1f("%-20s %s", service.Name, service.State)
+	}
+}
@@ -0,0 +1,122 @@
+package rscsrv
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Levels of the records written by the `JSONStarterReporter`.
+const (
+	JSONLevelInfo  = "info"
+	JSONLevelWarn  = "warn"
+	JSONLevelError = "error"
+)
+
+// JSONStarterReporter is a reporter that writes one JSON object per line for
+// each lifecycle event:
+//
+//	{"time":"2019-12-03T10:00:00.012Z","level":"info","service":"database","phase":"start","attempt":1,"duration_ms":12.3}
+//
+// It is also a `StartRetrierReporter`, reporting each attempt of a
+// `StartRetrier` with the "warn" level when it fails.
+type JSONStarterReporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// jsonRecord is the object written by the `JSONStarterReporter`.
+type jsonRecord struct {
+	Time       time.Time `json:"time"`
+	Level      string    `json:"level"`
+	Service    string    `json:"service"`
+	Phase      Phase     `json:"phase"`
+	Attempt    int       `json:"attempt,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// NewJSONStarterReporter returns a new `JSONStarterReporter` that writes to
+// w.
+func NewJSONStarterReporter(w io.Writer) *JSONStarterReporter {
+	return &JSONStarterReporter{
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// ReportEvent writes the event.
+func (reporter *JSONStarterReporter) ReportEvent(ctx context.Context, event LifecycleEvent) {
+	record := jsonRecord{
+		Time:       event.StartedAt.Add(event.Duration),
+		Level:      JSONLevelInfo,
+		Service:    event.Name,
+		Phase:      event.Phase,
+		Attempt:    event.Attempt,
+		DurationMs: float64(event.Duration) / float64(time.Millisecond),
+	}
+	if event.Err != nil {
+		record.Level = JSONLevelError
+		if event.Phase == PhaseStartAttempt {
+			record.Level = JSONLevelWarn
+		}
+		record.Error = event.Err.Error()
+	}
+	reporter.write(record)
+}
+
+// write encodes the record. Encoding errors are ignored, since there is
+// nowhere else to report them.
+func (reporter *JSONStarterReporter) write(record jsonRecord) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	_ = reporter.encoder.Encode(record)
+}
+
+// report writes a record for the `After*` callbacks, which are only called
+// when the reporter is not reached through `ReportEvent`.
+func (reporter *JSONStarterReporter) report(service interface{}, phase Phase, err error) {
+	var name string
+	if srv, ok := service.(Service); ok {
+		name = srv.Name()
+	}
+	reporter.ReportEvent(context.Background(), LifecycleEvent{
+		Name:      name,
+		Phase:     phase,
+		StartedAt: time.Now(),
+		Err:       err,
+	})
+}
+
+func (*JSONStarterReporter) BeforeBegin(service Service) {}
+
+func (*JSONStarterReporter) BeforeLoadConfiguration(service Configurable) {}
+
+func (reporter *JSONStarterReporter) AfterLoadConfiguration(service Configurable, conf interface{}, err error) {
+	reporter.report(service, PhaseLoadConfiguration, err)
+}
+
+func (*JSONStarterReporter) BeforeApplyConfiguration(service Configurable) {}
+
+func (reporter *JSONStarterReporter) AfterApplyConfiguration(service Configurable, conf interface{}, err error) {
+	reporter.report(service, PhaseApplyConfiguration, err)
+}
+
+func (*JSONStarterReporter) BeforeStart(service Service) {}
+
+func (reporter *JSONStarterReporter) AfterStart(service Service, err error) {
+	reporter.report(service, PhaseStart, err)
+}
+
+func (*JSONStarterReporter) BeforeStop(service Service) {}
+
+func (reporter *JSONStarterReporter) AfterStop(service Service, err error) {
+	reporter.report(service, PhaseStop, err)
+}
+
+// ReportRetrier returns err untouched. The attempts are written by
+// `ReportEvent`.
+func (*JSONStarterReporter) ReportRetrier(retrier *StartRetrier, err error) error {
+	return err
+}
@@ -0,0 +1,94 @@
+package rscsrv_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// jsonRecords decodes the records written by a `JSONStarterReporter`.
+func jsonRecords(buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		Expect(json.Unmarshal(scanner.Bytes(), &record)).To(Succeed())
+		records = append(records, record)
+	}
+	return records
+}
+
+var _ = Describe("JSONStarterReporter", func() {
+	It("should write one object per lifecycle event", func() {
+		var buf bytes.Buffer
+		engineStarter := rscsrv.NewServiceStarter(rscsrv.NewJSONStarterReporter(&buf), &MockService{
+			startDuration: time.Millisecond * 20,
+		})
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(engineStarter.Stop(false)).To(Succeed())
+
+		records := jsonRecords(&buf)
+		Expect(records).To(HaveLen(4))
+		phases := make([]interface{}, len(records))
+		for i, record := range records {
+			phases[i] = record["phase"]
+			Expect(record["service"]).To(Equal("mock-service"))
+			Expect(record["level"]).To(Equal("info"))
+			Expect(record["time"]).ToNot(BeEmpty())
+			Expect(record).ToNot(HaveKey("error"))
+		}
+		Expect(phases).To(Equal([]interface{}{"load configuration", "apply configuration", "start", "stop"}))
+		Expect(records[2]["duration_ms"]).To(BeNumerically(">=", 20))
+	})
+
+	It("should write the errors", func() {
+		var buf bytes.Buffer
+		engineStarter := rscsrv.NewServiceStarter(rscsrv.NewJSONStarterReporter(&buf), &MockService{
+			errStart: errors.New("start error"),
+		})
+		Expect(engineStarter.Start()).To(HaveOccurred())
+
+		records := jsonRecords(&buf)
+		Expect(records).To(HaveLen(3))
+		Expect(records[2]).To(HaveKeyWithValue("level", "error"))
+		Expect(records[2]).To(HaveKeyWithValue("error", "start error"))
+	})
+
+	It("should write the attempts of a StartRetrier", func() {
+		var buf bytes.Buffer
+		reporter := rscsrv.NewJSONStarterReporter(&buf)
+		retrier := rscsrv.NewStartRetrier(&retrierMockService{successAt: 2}, rscsrv.StartRetrierOptions{
+			DelayBetweenTries: time.Millisecond,
+			Reporter:          reporter,
+		})
+		Expect(retrier.(rscsrv.Startable).Start()).To(Succeed())
+
+		records := jsonRecords(&buf)
+		Expect(records).To(HaveLen(2))
+		Expect(records[0]).To(HaveKeyWithValue("phase", "start attempt"))
+		Expect(records[0]).To(HaveKeyWithValue("level", "warn"))
+		Expect(records[0]).To(HaveKeyWithValue("attempt", BeNumerically("==", 1)))
+		Expect(records[0]).To(HaveKeyWithValue("error", "failed to start"))
+		Expect(records[1]).To(HaveKeyWithValue("level", "info"))
+		Expect(records[1]).To(HaveKeyWithValue("attempt", BeNumerically("==", 2)))
+	})
+
+	It("should write the After callbacks", func() {
+		var buf bytes.Buffer
+		reporter := rscsrv.NewJSONStarterReporter(&buf)
+		reporter.AfterStop(&MockService{}, errors.New("stop error"))
+
+		records := jsonRecords(&buf)
+		Expect(records).To(HaveLen(1))
+		Expect(records[0]).To(HaveKeyWithValue("service", "mock-service"))
+		Expect(records[0]).To(HaveKeyWithValue("phase", "stop"))
+		Expect(records[0]).To(HaveKeyWithValue("error", "stop error"))
+	})
+})
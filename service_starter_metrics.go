@@ -0,0 +1,317 @@
+package rscsrv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMetricsBuckets are the upper bounds, in seconds, of the buckets of
+// the phase duration histogram.
+var DefaultMetricsBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// MetricsReporterOptions defines the options for the
+// `MetricsStarterReporter`.
+type MetricsReporterOptions struct {
+	// Namespace prefixes the name of all metrics. If empty, "rscsrv" will be
+	// used.
+	Namespace string
+
+	// Buckets are the upper bounds, in seconds, of the buckets of the phase
+	// duration histogram. If empty, `DefaultMetricsBuckets` will be used.
+	Buckets []float64
+}
+
+// MetricsStarterReporter is a reporter that records metrics of the lifecycle
+// of the services and exposes them, as an `http.Handler`, in the Prometheus
+// text exposition format:
+//
+//   - <namespace>_phase_duration_seconds: histogram of the duration of each
+//     phase, by service and phase;
+//   - <namespace>_phase_failures_total: failures of each phase, by service and
+//     phase;
+//   - <namespace>_service_starts_total: successful starts, by service;
+//   - <namespace>_service_restarts_total: successful starts after the first
+//     one, by service;
+//   - <namespace>_retry_attempts_total: attempts of a `StartRetrier`, by
+//     service and result ("success" or "failure");
+//   - <namespace>_service_state: 1 for the current state of each service, as
+//     returned by `ServiceStarter.State`, 0 for the others.
+//
+// It is also a `StartRetrierReporter`, so it can be used as the `Reporter` of
+// a `StartRetrier`, and a `ServiceStateReporter`.
+type MetricsStarterReporter struct {
+	namespace string
+	buckets   []float64
+
+	mutex     sync.Mutex
+	durations map[metricsKey]*metricsHistogram
+	failures  map[metricsKey]int
+	starts    map[string]int
+	attempts  map[metricsKey]int
+	states    map[string]ServiceState
+}
+
+// metricsKey identifies a series by its labels.
+type metricsKey struct {
+	service string
+	label   string
+}
+
+type metricsHistogram struct {
+	counts []int
+	sum    float64
+	count  int
+}
+
+// NewMetricsStarterReporter returns a new `MetricsStarterReporter`.
+func NewMetricsStarterReporter(options MetricsReporterOptions) *MetricsStarterReporter {
+	if options.Namespace == "" {
+		options.Namespace = "rscsrv"
+	}
+	if len(options.Buckets) == 0 {
+		options.Buckets = DefaultMetricsBuckets
+	}
+	buckets := append([]float64{}, options.Buckets...)
+	sort.Float64s(buckets)
+	return &MetricsStarterReporter{
+		namespace: options.Namespace,
+		buckets:   buckets,
+		durations: make(map[metricsKey]*metricsHistogram),
+		failures:  make(map[metricsKey]int),
+		starts:    make(map[string]int),
+		attempts:  make(map[metricsKey]int),
+		states:    make(map[string]ServiceState),
+	}
+}
+
+// ReportEvent records the event.
+func (reporter *MetricsStarterReporter) ReportEvent(ctx context.Context, event LifecycleEvent) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	if event.Phase == PhaseStartAttempt {
+		result := "success"
+		if event.Err != nil {
+			result = "failure"
+		}
+		reporter.attempts[metricsKey{event.Name, result}]++
+		return
+	}
+
+	key := metricsKey{event.Name, string(event.Phase)}
+	histogram, ok := reporter.durations[key]
+	if !ok {
+		histogram = &metricsHistogram{counts: make([]int, len(reporter.buckets))}
+		reporter.durations[key] = histogram
+	}
+	seconds := event.Duration.Seconds()
+	for i, bucket := range reporter.buckets {
+		if seconds <= bucket {
+			histogram.counts[i]++
+		}
+	}
+	histogram.sum += seconds
+	histogram.count++
+
+	reporter.record(event.Name, event.Phase, event.Err)
+}
+
+// record counts the result of the phase. It must be called with the mutex
+// locked.
+func (reporter *MetricsStarterReporter) record(service string, phase Phase, err error) {
+	if err != nil {
+		reporter.failures[metricsKey{service, string(phase)}]++
+		return
+	}
+	if phase == PhaseStart {
+		reporter.starts[service]++
+	}
+}
+
+// ReportState records the state of the service, as returned by
+// `ServiceStarter.State`.
+func (reporter *MetricsStarterReporter) ReportState(service Service, state ServiceState, err error) {
+	reporter.mutex.Lock()
+	reporter.states[service.Name()] = state
+	reporter.mutex.Unlock()
+}
+
+// after records the `After*` callbacks, which are only called when the
+// reporter is not reached through `ReportEvent`. No duration is recorded.
+func (reporter *MetricsStarterReporter) after(service interface{}, phase Phase, err error) {
+	srv, ok := service.(Service)
+	if !ok {
+		return
+	}
+	reporter.mutex.Lock()
+	reporter.record(srv.Name(), phase, err)
+	reporter.mutex.Unlock()
+}
+
+func (reporter *MetricsStarterReporter) BeforeBegin(service Service) {
+	reporter.mutex.Lock()
+	if _, ok := reporter.states[service.Name()]; !ok {
+		reporter.states[service.Name()] = StatePending
+	}
+	reporter.mutex.Unlock()
+}
+
+func (*MetricsStarterReporter) BeforeLoadConfiguration(service Configurable) {}
+
+func (reporter *MetricsStarterReporter) AfterLoadConfiguration(service Configurable, conf interface{}, err error) {
+	reporter.after(service, PhaseLoadConfiguration, err)
+}
+
+func (*MetricsStarterReporter) BeforeApplyConfiguration(service Configurable) {}
+
+func (reporter *MetricsStarterReporter) AfterApplyConfiguration(service Configurable, conf interface{}, err error) {
+	reporter.after(service, PhaseApplyConfiguration, err)
+}
+
+func (*MetricsStarterReporter) BeforeStart(service Service) {}
+
+func (reporter *MetricsStarterReporter) AfterStart(service Service, err error) {
+	reporter.after(service, PhaseStart, err)
+}
+
+func (*MetricsStarterReporter) BeforeStop(service Service) {}
+
+func (reporter *MetricsStarterReporter) AfterStop(service Service, err error) {
+	reporter.after(service, PhaseStop, err)
+}
+
+// ReportRetrier returns err untouched. The attempts are recorded by
+// `ReportEvent`.
+func (*MetricsStarterReporter) ReportRetrier(retrier *StartRetrier, err error) error {
+	return err
+}
+
+var metricsStates = []ServiceState{
+	StatePending,
+	StateConfiguring,
+	StateStarting,
+	StateStartingInBackground,
+	StateRunning,
+	StateStopping,
+	StateStopped,
+	StateFailed,
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (reporter *MetricsStarterReporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(reporter.Bytes())
+}
+
+// Bytes returns the metrics in the Prometheus text exposition format.
+func (reporter *MetricsStarterReporter) Bytes() []byte {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	var buf bytes.Buffer
+	name := reporter.namespace + "_phase_duration_seconds"
+	writeMetricsHeader(&buf, name, "histogram", "Duration of the lifecycle phases of the services.")
+	keys := make([]metricsKey, 0, len(reporter.durations))
+	for key := range reporter.durations {
+		keys = append(keys, key)
+	}
+	sortMetricsKeys(keys)
+	for _, key := range keys {
+		histogram := reporter.durations[key]
+		labels := fmt.Sprintf(`service="%s",phase="%s"`, escapeLabel(key.service), escapeLabel(key.label))
+		for i, bucket := range reporter.buckets {
+			fmt.Fprintf(&buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bucket), histogram.counts[i])
+		}
+		fmt.Fprintf(&buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, histogram.count)
+		fmt.Fprintf(&buf, "%s_sum{%s} %s\n", name, labels, formatFloat(histogram.sum))
+		fmt.Fprintf(&buf, "%s_count{%s} %d\n", name, labels, histogram.count)
+	}
+
+	name = reporter.namespace + "_phase_failures_total"
+	writeMetricsHeader(&buf, name, "counter", "Failures of the lifecycle phases of the services.")
+	for _, key := range sortedMetricsKeys(reporter.failures) {
+		fmt.Fprintf(&buf, "%s{service=\"%s\",phase=\"%s\"} %d\n", name, escapeLabel(key.service), escapeLabel(key.label), reporter.failures[key])
+	}
+
+	services := make([]string, 0, len(reporter.states))
+	for service := range reporter.states {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	name = reporter.namespace + "_service_starts_total"
+	writeMetricsHeader(&buf, name, "counter", "Successful starts of the services.")
+	for _, service := range services {
+		fmt.Fprintf(&buf, "%s{service=\"%s\"} %d\n", name, escapeLabel(service), reporter.starts[service])
+	}
+
+	name = reporter.namespace + "_service_restarts_total"
+	writeMetricsHeader(&buf, name, "counter", "Successful starts of the services after the first one.")
+	for _, service := range services {
+		restarts := reporter.starts[service] - 1
+		if restarts < 0 {
+			restarts = 0
+		}
+		fmt.Fprintf(&buf, "%s{service=\"%s\"} %d\n", name, escapeLabel(service), restarts)
+	}
+
+	name = reporter.namespace + "_retry_attempts_total"
+	writeMetricsHeader(&buf, name, "counter", "Attempts of starting the services by a StartRetrier.")
+	for _, key := range sortedMetricsKeys(reporter.attempts) {
+		fmt.Fprintf(&buf, "%s{service=\"%s\",result=\"%s\"} %d\n", name, escapeLabel(key.service), key.label, reporter.attempts[key])
+	}
+
+	name = reporter.namespace + "_service_state"
+	writeMetricsHeader(&buf, name, "gauge", "Current state of the services.")
+	for _, service := range services {
+		for _, state := range metricsStates {
+			value := 0
+			if reporter.states[service] == state {
+				value = 1
+			}
+			fmt.Fprintf(&buf, "%s{service=\"%s\",state=\"%s\"} %d\n", name, escapeLabel(service), state, value)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeMetricsHeader(buf *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sortedMetricsKeys returns the keys of the counters sorted, so the output is
+// stable.
+func sortedMetricsKeys(counters map[metricsKey]int) []metricsKey {
+	keys := make([]metricsKey, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sortMetricsKeys(keys)
+	return keys
+}
+
+func sortMetricsKeys(keys []metricsKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		return keys[i].label < keys[j].label
+	})
+}
+
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value for the text exposition format.
+func escapeLabel(value string) string {
+	return labelReplacer.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
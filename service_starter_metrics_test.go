@@ -0,0 +1,118 @@
+package rscsrv_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+var _ = Describe("MetricsStarterReporter", func() {
+	It("should expose the metrics of the lifecycle of the services", func() {
+		reporter := rscsrv.NewMetricsStarterReporter(rscsrv.MetricsReporterOptions{
+			Buckets: []float64{0.01, 1},
+		})
+		engineStarter := rscsrv.NewServiceStarter(reporter,
+			&MockDependentService{name: "database", recorder: &orderRecorder{}, MockService: MockService{startDuration: time.Millisecond * 20}},
+			&MockDependentService{name: "cache", recorder: &orderRecorder{}, MockService: MockService{errStop: errors.New("stop error")}},
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(engineStarter.Stop(true)).To(HaveOccurred())
+
+		recorder := httptest.NewRecorder()
+		reporter.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+
+		body := recorder.Body.String()
+		Expect(body).To(ContainSubstring("# TYPE rscsrv_phase_duration_seconds histogram\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_phase_duration_seconds_bucket{service="database",phase="start",le="0.01"} 0` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_phase_duration_seconds_bucket{service="database",phase="start",le="1"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_phase_duration_seconds_bucket{service="database",phase="start",le="+Inf"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_phase_duration_seconds_count{service="database",phase="start"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_phase_failures_total{service="cache",phase="stop"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_starts_total{service="database"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_restarts_total{service="database"} 0` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="database",state="stopped"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="database",state="running"} 0` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="cache",state="failed"} 1` + "\n"))
+	})
+
+	It("should count the restarts of the services", func() {
+		reporter := rscsrv.NewMetricsStarterReporter(rscsrv.MetricsReporterOptions{Namespace: "app"})
+		engineStarter := rscsrv.NewServiceStarter(reporter, &MockDependentService{name: "database", recorder: &orderRecorder{}})
+		for i := 0; i < 3; i++ {
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		}
+
+		body := string(reporter.Bytes())
+		Expect(body).To(ContainSubstring(`app_service_starts_total{service="database"} 3` + "\n"))
+		Expect(body).To(ContainSubstring(`app_service_restarts_total{service="database"} 2` + "\n"))
+	})
+
+	It("should expose the services starting in background", func() {
+		reporter := rscsrv.NewMetricsStarterReporter(rscsrv.MetricsReporterOptions{})
+		retrier := rscsrv.NewStartRetrier(&retrierMockService{successAt: 2}, rscsrv.StartRetrierOptions{
+			DelayBetweenTries: time.Millisecond * 50,
+			Reporter:          &retrierMockReporter{},
+			Background:        true,
+		})
+		engineStarter := rscsrv.NewServiceStarter(reporter, retrier)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		body := string(reporter.Bytes())
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="retrierMockService",state="starting in background"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="retrierMockService",state="starting"} 0` + "\n"))
+
+		<-retrier.(readyNotifier).Ready()
+		Eventually(func() string {
+			return string(reporter.Bytes())
+		}).Should(ContainSubstring(`rscsrv_service_state{service="retrierMockService",state="running"} 1` + "\n"))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should expose the state of the services as reported by the starter", func() {
+		reporter := rscsrv.NewMetricsStarterReporter(rscsrv.MetricsReporterOptions{})
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Reporter:   reporter,
+			Supervisor: rscsrv.SupervisorOptions{Policy: rscsrv.RestartNever},
+		},
+			&configOnlyService{name: "settings"},
+			&MockRunnableService{name: "consumer", failures: -1},
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+		Eventually(func() rscsrv.ServiceState {
+			return stateOf(engineStarter, "consumer")
+		}).Should(Equal(rscsrv.StateFailed))
+
+		body := string(reporter.Bytes())
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="settings",state="running"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="settings",state="configuring"} 0` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="consumer",state="failed"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="consumer",state="running"} 0` + "\n"))
+
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		body = string(reporter.Bytes())
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="settings",state="stopped"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_service_state{service="settings",state="running"} 0` + "\n"))
+	})
+
+	It("should count the attempts of a StartRetrier", func() {
+		reporter := rscsrv.NewMetricsStarterReporter(rscsrv.MetricsReporterOptions{})
+		retrier := rscsrv.NewStartRetrier(&retrierMockService{successAt: 3}, rscsrv.StartRetrierOptions{
+			DelayBetweenTries: time.Millisecond,
+			Reporter:          reporter,
+		})
+		Expect(retrier.(rscsrv.Startable).Start()).To(Succeed())
+
+		body := string(reporter.Bytes())
+		Expect(body).To(ContainSubstring(`rscsrv_retry_attempts_total{service="retrierMockService",result="failure"} 2` + "\n"))
+		Expect(body).To(ContainSubstring(`rscsrv_retry_attempts_total{service="retrierMockService",result="success"} 1` + "\n"))
+	})
+})
@@ -0,0 +1,181 @@
+package rscsrv
+
+import (
+	"context"
+	"sync"
+)
+
+// MultiStarterReporter forwards every callback to a list of reporters, in
+// the order they were added. A panic in one reporter is recovered, so it
+// does not prevent the others from being called.
+//
+// Besides the `ServiceStarterReporter` callbacks, it forwards
+// `ServiceStarterEventReporter`, `ServiceStarterSequenceReporter`,
+// `StartRetrierReporter`, `CircuitBreakerReporter` and `SnapshotReporter`
+// calls to the reporters that implement them. Lifecycle events are delivered
+// to the other reporters through the `After*` callbacks.
+type MultiStarterReporter struct {
+	mutex     sync.RWMutex
+	reporters []ServiceStarterReporter
+}
+
+// NewMultiStarterReporter returns a new `MultiStarterReporter` forwarding to
+// the given reporters.
+func NewMultiStarterReporter(reporters ...ServiceStarterReporter) *MultiStarterReporter {
+	return &MultiStarterReporter{
+		reporters: reporters,
+	}
+}
+
+// Add appends reporters to the list. It is safe to call it while services
+// are being started.
+func (reporter *MultiStarterReporter) Add(reporters ...ServiceStarterReporter) {
+	reporter.mutex.Lock()
+	reporter.reporters = append(reporter.reporters, reporters...)
+	reporter.mutex.Unlock()
+}
+
+// forEach calls fnc with each reporter, recovering from its panics.
+func (reporter *MultiStarterReporter) forEach(fnc func(r ServiceStarterReporter)) {
+	reporter.mutex.RLock()
+	reporters := reporter.reporters
+	reporter.mutex.RUnlock()
+	for _, r := range reporters {
+		func() {
+			defer func() {
+				// A broken reporter must not break the others.
+				_ = recover()
+			}()
+			fnc(r)
+		}()
+	}
+}
+
+func (reporter *MultiStarterReporter) BeforeBegin(service Service) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.BeforeBegin(service)
+	})
+}
+
+func (reporter *MultiStarterReporter) BeforeLoadConfiguration(service Configurable) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.BeforeLoadConfiguration(service)
+	})
+}
+
+func (reporter *MultiStarterReporter) AfterLoadConfiguration(service Configurable, conf interface{}, err error) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.AfterLoadConfiguration(service, conf, err)
+	})
+}
+
+func (reporter *MultiStarterReporter) BeforeApplyConfiguration(service Configurable) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.BeforeApplyConfiguration(service)
+	})
+}
+
+func (reporter *MultiStarterReporter) AfterApplyConfiguration(service Configurable, conf interface{}, err error) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.AfterApplyConfiguration(service, conf, err)
+	})
+}
+
+func (reporter *MultiStarterReporter) BeforeStart(service Service) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.BeforeStart(service)
+	})
+}
+
+func (reporter *MultiStarterReporter) AfterStart(service Service, err error) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.AfterStart(service, err)
+	})
+}
+
+func (reporter *MultiStarterReporter) BeforeStop(service Service) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.BeforeStop(service)
+	})
+}
+
+func (reporter *MultiStarterReporter) AfterStop(service Service, err error) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		r.AfterStop(service, err)
+	})
+}
+
+// ReportEvent forwards the event to the `ServiceStarterEventReporter`s, and
+// to the `After*` callbacks of the other reporters.
+func (reporter *MultiStarterReporter) ReportEvent(ctx context.Context, event LifecycleEvent) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		reportEvent(ctx, r, event)
+	})
+}
+
+// BeginSequence forwards the call to the `ServiceStarterSequenceReporter`s.
+func (reporter *MultiStarterReporter) BeginSequence(ctx context.Context, sequence Sequence) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		if sequenceReporter, ok := r.(ServiceStarterSequenceReporter); ok {
+			sequenceReporter.BeginSequence(ctx, sequence)
+		}
+	})
+}
+
+// EndSequence forwards the call to the `ServiceStarterSequenceReporter`s.
+func (reporter *MultiStarterReporter) EndSequence(ctx context.Context, sequence Sequence, err error) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		if sequenceReporter, ok := r.(ServiceStarterSequenceReporter); ok {
+			sequenceReporter.EndSequence(ctx, sequence, err)
+		}
+	})
+}
+
+// ReportRetrier forwards the error to the `StartRetrierReporter`s. Each one
+// receives the error returned by the previous one.
+func (reporter *MultiStarterReporter) ReportRetrier(retrier *StartRetrier, err error) error {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		if retrierReporter, ok := r.(StartRetrierReporter); ok {
+			err = retrierReporter.ReportRetrier(retrier, err)
+		}
+	})
+	return err
+}
+
+// ReportCircuitBreaker forwards the transition to the
+// `CircuitBreakerReporter`s.
+func (reporter *MultiStarterReporter) ReportCircuitBreaker(breaker *CircuitBreaker, from, to CircuitState, err error) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		if breakerReporter, ok := r.(CircuitBreakerReporter); ok {
+			breakerReporter.ReportCircuitBreaker(breaker, from, to, err)
+		}
+	})
+}
+
+// ReportSnapshot forwards the snapshot to the `SnapshotReporter`s.
+func (reporter *MultiStarterReporter) ReportSnapshot(snapshot Snapshot) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		if snapshotReporter, ok := r.(SnapshotReporter); ok {
+			snapshotReporter.ReportSnapshot(snapshot)
+		}
+	})
+}
+
+// ReportReload forwards the result of the reload to the `ReloadReporter`s.
+func (reporter *MultiStarterReporter) ReportReload(err error) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		if reloadReporter, ok := r.(ReloadReporter); ok {
+			reloadReporter.ReportReload(err)
+		}
+	})
+}
+
+// ReportState forwards the state of the service to the
+// `ServiceStateReporter`s.
+func (reporter *MultiStarterReporter) ReportState(service Service, state ServiceState, err error) {
+	reporter.forEach(func(r ServiceStarterReporter) {
+		if stateReporter, ok := r.(ServiceStateReporter); ok {
+			stateReporter.ReportState(service, state, err)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package rscsrv_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// panicReporter panics on every callback.
+type panicReporter struct{}
+
+func (*panicReporter) BeforeBegin(service rscsrv.Service) { panic("reporter") }
+
+func (*panicReporter) BeforeLoadConfiguration(service rscsrv.Configurable) { panic("reporter") }
+
+func (*panicReporter) AfterLoadConfiguration(service rscsrv.Configurable, conf interface{}, err error) {
+	panic("reporter")
+}
+
+func (*panicReporter) BeforeApplyConfiguration(service rscsrv.Configurable) { panic("reporter") }
+
+func (*panicReporter) AfterApplyConfiguration(service rscsrv.Configurable, conf interface{}, err error) {
+	panic("reporter")
+}
+
+func (*panicReporter) BeforeStart(service rscsrv.Service) { panic("reporter") }
+
+func (*panicReporter) AfterStart(service rscsrv.Service, err error) { panic("reporter") }
+
+func (*panicReporter) BeforeStop(service rscsrv.Service) { panic("reporter") }
+
+func (*panicReporter) AfterStop(service rscsrv.Service, err error) { panic("reporter") }
+
+func (*panicReporter) ReportEvent(ctx context.Context, event rscsrv.LifecycleEvent) {
+	panic("reporter")
+}
+
+// retrierCountReporter is a `ServiceStarterReporter` that counts the
+// `ReportRetrier` calls.
+type retrierCountReporter struct {
+	countEngineReporter
+	retrierMockReporter
+}
+
+var _ = Describe("MultiStarterReporter", func() {
+	It("should forward the callbacks to all reporters", func() {
+		countReporter := &countEngineReporter{}
+		events := &eventRecorder{}
+		reporter := rscsrv.NewMultiStarterReporter(&panicReporter{}, countReporter)
+		reporter.Add(events)
+
+		engineStarter := rscsrv.NewServiceStarter(reporter, &MockService{})
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(engineStarter.Stop(false)).To(Succeed())
+
+		Expect(countReporter.countBeforeBegin).To(Equal(2))
+		Expect(countReporter.countBeforeLoadConfiguration).To(Equal(1))
+		Expect(countReporter.countAfterLoadConfiguration).To(Equal(1))
+		Expect(countReporter.countBeforeApplyConfiguration).To(Equal(1))
+		Expect(countReporter.countAfterApplyConfiguration).To(Equal(1))
+		Expect(countReporter.countBeforeStart).To(Equal(1))
+		Expect(countReporter.countAfterStart).To(Equal(1))
+		Expect(countReporter.countBeforeStop).To(Equal(1))
+		Expect(countReporter.countAfterStop).To(Equal(1))
+
+		Expect(events.Phases()).To(Equal([]rscsrv.Phase{
+			rscsrv.PhaseLoadConfiguration,
+			rscsrv.PhaseApplyConfiguration,
+			rscsrv.PhaseStart,
+			rscsrv.PhaseStop,
+		}))
+		Expect(events.countAfterStart).To(Equal(0))
+	})
+
+	It("should forward the retrier reports", func() {
+		retrierReporter := &retrierCountReporter{}
+		events := &eventRecorder{}
+		reporter := rscsrv.NewMultiStarterReporter(&panicReporter{}, retrierReporter, events)
+
+		retrier := rscsrv.NewStartRetrier(&retrierMockService{successAt: 3}, rscsrv.StartRetrierOptions{
+			DelayBetweenTries: time.Millisecond,
+			Reporter:          reporter,
+		})
+		Expect(retrier.(rscsrv.Startable).Start()).To(Succeed())
+
+		Expect(retrierReporter.retrierMockReporter.count).To(Equal(2))
+		Expect(retrierReporter.countAfterStart).To(Equal(0))
+		Expect(events.Phases()).To(Equal([]rscsrv.Phase{
+			rscsrv.PhaseStartAttempt,
+			rscsrv.PhaseStartAttempt,
+			rscsrv.PhaseStartAttempt,
+		}))
+	})
+})
@@ -1,5 +1,11 @@
 package rscsrv
 
+import (
+	"context"
+	"sync"
+	"time"
+)
+
 type ServiceStarterReporter interface {
 	BeforeBegin(service Service)
 
@@ -15,3 +21,316 @@ type ServiceStarterReporter interface {
 	BeforeStop(service Service)
 	AfterStop(service Service, err error)
 }
+
+// LifecycleEvent describes a lifecycle phase of a service that just finished.
+type LifecycleEvent struct {
+	// Service is the service the phase belongs to.
+	Service Service
+	// Name is the name of the service.
+	Name string
+	// Phase is the lifecycle phase.
+	Phase Phase
+	// Configuration is the configuration loaded or applied, if any.
+	Configuration interface{}
+	// StartedAt is when the phase started.
+	StartedAt time.Time
+	// Duration is how long the phase took.
+	Duration time.Duration
+	// Attempt is the number of the attempt, starting at 1. It is greater
+	// than 1 only for the retries of a `StartRetrier`.
+	Attempt int
+	// Err is the error of the phase, if it failed.
+	Err error
+}
+
+// ServiceStarterEventReporter is an optional interface for
+// `ServiceStarterReporter`s that receive a `LifecycleEvent` when each phase
+// finishes. When implemented, `ReportEvent` is called instead of the `After*`
+// callbacks.
+type ServiceStarterEventReporter interface {
+	// ReportEvent is called with the context of the phase when it finishes.
+	ReportEvent(ctx context.Context, event LifecycleEvent)
+}
+
+// Sequence identifies the start, or the stop, of all services.
+type Sequence string
+
+const (
+	// SequenceStart is the start of all services.
+	SequenceStart Sequence = "start"
+	// SequenceStop is the stop of all services.
+	SequenceStop Sequence = "stop"
+)
+
+// sequenceKey is the context key of the `sequenceRun` that the phases
+// reported with the context belong to.
+type sequenceKey struct{}
+
+// sequenceRun identifies each start, or stop, of a `ServiceStarter`, so the
+// sequences of different starters reporting to the same reporter are told
+// apart.
+type sequenceRun struct {
+	sequence Sequence
+}
+
+// withSequence returns a context that carries a new run of the sequence.
+func withSequence(ctx context.Context, sequence Sequence) context.Context {
+	return context.WithValue(ctx, sequenceKey{}, &sequenceRun{sequence})
+}
+
+// sequenceOf returns the run of the sequence carried by ctx, if any.
+func sequenceOf(ctx context.Context) *sequenceRun {
+	run, _ := ctx.Value(sequenceKey{}).(*sequenceRun)
+	return run
+}
+
+// ServiceStarterSequenceReporter is an optional interface for
+// `ServiceStarterReporter`s that are notified when the `ServiceStarter`
+// begins and ends starting, or stopping, all services.
+type ServiceStarterSequenceReporter interface {
+	// BeginSequence is called before the first service is touched.
+	BeginSequence(ctx context.Context, sequence Sequence)
+	// EndSequence is called with the error returned by the `ServiceStarter`,
+	// if any.
+	EndSequence(ctx context.Context, sequence Sequence, err error)
+}
+
+func newLifecycleEvent(service Service, phase Phase, startedAt time.Time, conf interface{}, err error) LifecycleEvent {
+	return LifecycleEvent{
+		Service:       service,
+		Name:          service.Name(),
+		Phase:         phase,
+		Configuration: conf,
+		StartedAt:     startedAt,
+		Duration:      time.Since(startedAt),
+		Attempt:       1,
+		Err:           err,
+	}
+}
+
+// reportEvent reports the event to the reporter, through `ReportEvent` if it
+// is a `ServiceStarterEventReporter`, or through the matching `After*`
+// callback otherwise.
+func reportEvent(ctx context.Context, reporter ServiceStarterReporter, event LifecycleEvent) {
+	if eventReporter, ok := reporter.(ServiceStarterEventReporter); ok {
+		eventReporter.ReportEvent(ctx, event)
+		return
+	}
+	configurable, _ := asConfigurable(event.Service)
+	switch event.Phase {
+	case PhaseLoadConfiguration:
+		reporter.AfterLoadConfiguration(configurable, event.Configuration, event.Err)
+	case PhaseApplyConfiguration:
+		reporter.AfterApplyConfiguration(configurable, event.Configuration, event.Err)
+	case PhaseStart:
+		reporter.AfterStart(event.Service, event.Err)
+	case PhaseStop:
+		reporter.AfterStop(event.Service, event.Err)
+	}
+}
+
+// syncStarterReporter serializes the calls to a `ServiceStarterReporter`
+// that is used by services starting concurrently.
+//
+// During a parallel start, the calls of each service are kept together: the
+// calls of the first service still starting go through right away, while
+// the ones of the others are buffered until the services before them are
+// done.
+type syncStarterReporter struct {
+	mutex    sync.Mutex
+	reporter ServiceStarterReporter
+
+	buffering bool
+	blocks    []*reporterBlock
+}
+
+// reporterBlock keeps the calls of a service during a parallel start.
+type reporterBlock struct {
+	name  string
+	calls []func()
+	ended bool
+}
+
+// call runs fnc, the call of the service with the given name, or buffers it
+// if another service is being reported.
+func (reporter *syncStarterReporter) call(name string, fnc func()) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	for i, block := range reporter.blocks {
+		if block.name != name {
+			continue
+		}
+		if i > 0 {
+			block.calls = append(block.calls, fnc)
+			return
+		}
+		break
+	}
+	fnc()
+}
+
+// beginParallel starts keeping the calls of each service together.
+func (reporter *syncStarterReporter) beginParallel() {
+	reporter.mutex.Lock()
+	reporter.buffering = true
+	reporter.mutex.Unlock()
+}
+
+// endParallel flushes the calls still buffered and stops buffering.
+func (reporter *syncStarterReporter) endParallel() {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	for _, block := range reporter.blocks {
+		for _, fnc := range block.calls {
+			fnc()
+		}
+	}
+	reporter.blocks = nil
+	reporter.buffering = false
+}
+
+// endService is called when the `ServiceStarter` is done starting the
+// service. If it was the first service, the calls buffered for the next
+// ones are flushed.
+func (reporter *syncStarterReporter) endService(service Service) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	for _, block := range reporter.blocks {
+		if block.name == service.Name() && !block.ended {
+			block.ended = true
+			break
+		}
+	}
+	for len(reporter.blocks) > 0 && reporter.blocks[0].ended {
+		reporter.blocks = reporter.blocks[1:]
+		if len(reporter.blocks) == 0 {
+			break
+		}
+		next := reporter.blocks[0]
+		for _, fnc := range next.calls {
+			fnc()
+		}
+		next.calls = nil
+	}
+}
+
+// configurableName returns the name of the service, if it is a `Service`.
+func configurableName(service Configurable) string {
+	if srv, ok := service.(Service); ok {
+		return srv.Name()
+	}
+	return ""
+}
+
+func (reporter *syncStarterReporter) BeforeBegin(service Service) {
+	reporter.mutex.Lock()
+	if reporter.buffering {
+		reporter.blocks = append(reporter.blocks, &reporterBlock{name: service.Name()})
+	}
+	reporter.mutex.Unlock()
+	reporter.call(service.Name(), func() {
+		reporter.reporter.BeforeBegin(service)
+	})
+}
+
+func (reporter *syncStarterReporter) BeforeLoadConfiguration(service Configurable) {
+	reporter.call(configurableName(service), func() {
+		reporter.reporter.BeforeLoadConfiguration(service)
+	})
+}
+
+func (reporter *syncStarterReporter) AfterLoadConfiguration(service Configurable, conf interface{}, err error) {
+	reporter.call(configurableName(service), func() {
+		reporter.reporter.AfterLoadConfiguration(service, conf, err)
+	})
+}
+
+func (reporter *syncStarterReporter) BeforeApplyConfiguration(service Configurable) {
+	reporter.call(configurableName(service), func() {
+		reporter.reporter.BeforeApplyConfiguration(service)
+	})
+}
+
+func (reporter *syncStarterReporter) AfterApplyConfiguration(service Configurable, conf interface{}, err error) {
+	reporter.call(configurableName(service), func() {
+		reporter.reporter.AfterApplyConfiguration(service, conf, err)
+	})
+}
+
+func (reporter *syncStarterReporter) BeforeStart(service Service) {
+	reporter.call(service.Name(), func() {
+		reporter.reporter.BeforeStart(service)
+	})
+}
+
+func (reporter *syncStarterReporter) AfterStart(service Service, err error) {
+	reporter.call(service.Name(), func() {
+		reporter.reporter.AfterStart(service, err)
+	})
+}
+
+func (reporter *syncStarterReporter) BeforeStop(service Service) {
+	reporter.call(service.Name(), func() {
+		reporter.reporter.BeforeStop(service)
+	})
+}
+
+func (reporter *syncStarterReporter) AfterStop(service Service, err error) {
+	reporter.call(service.Name(), func() {
+		reporter.reporter.AfterStop(service, err)
+	})
+}
+
+func (reporter *syncStarterReporter) ReportEvent(ctx context.Context, event LifecycleEvent) {
+	reporter.call(event.Name, func() {
+		reportEvent(ctx, reporter.reporter, event)
+	})
+}
+
+func (reporter *syncStarterReporter) ReportState(service Service, state ServiceState, err error) {
+	if stateReporter, ok := reporter.reporter.(ServiceStateReporter); ok {
+		reporter.mutex.Lock()
+		defer reporter.mutex.Unlock()
+		stateReporter.ReportState(service, state, err)
+	}
+}
+
+func (reporter *syncStarterReporter) BeginSequence(ctx context.Context, sequence Sequence) {
+	if sequenceReporter, ok := reporter.reporter.(ServiceStarterSequenceReporter); ok {
+		reporter.mutex.Lock()
+		defer reporter.mutex.Unlock()
+		sequenceReporter.BeginSequence(ctx, sequence)
+	}
+}
+
+func (reporter *syncStarterReporter) EndSequence(ctx context.Context, sequence Sequence, err error) {
+	if sequenceReporter, ok := reporter.reporter.(ServiceStarterSequenceReporter); ok {
+		reporter.mutex.Lock()
+		defer reporter.mutex.Unlock()
+		sequenceReporter.EndSequence(ctx, sequence, err)
+	}
+}
+
+func (reporter *syncStarterReporter) ReportCircuitBreaker(breaker *CircuitBreaker, from, to CircuitState, err error) {
+	if breakerReporter, ok := reporter.reporter.(CircuitBreakerReporter); ok {
+		reporter.mutex.Lock()
+		defer reporter.mutex.Unlock()
+		breakerReporter.ReportCircuitBreaker(breaker, from, to, err)
+	}
+}
+
+func (reporter *syncStarterReporter) ReportSnapshot(snapshot Snapshot) {
+	if snapshotReporter, ok := reporter.reporter.(SnapshotReporter); ok {
+		reporter.mutex.Lock()
+		defer reporter.mutex.Unlock()
+		snapshotReporter.ReportSnapshot(snapshot)
+	}
+}
+
+func (reporter *syncStarterReporter) ReportReload(err error) {
+	if reloadReporter, ok := reporter.reporter.(ReloadReporter); ok {
+		reporter.mutex.Lock()
+		defer reporter.mutex.Unlock()
+		reloadReporter.ReportReload(err)
+	}
+}
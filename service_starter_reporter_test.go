@@ -0,0 +1,213 @@
+package rscsrv_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// callRecorder is a `ServiceStarterReporter` that records the service of
+// each callback.
+type callRecorder struct {
+	rscsrv.NopStarterReporter
+	names []string
+}
+
+func (reporter *callRecorder) record(service interface{}) {
+	reporter.names = append(reporter.names, service.(rscsrv.Service).Name())
+}
+
+func (reporter *callRecorder) BeforeBegin(service rscsrv.Service) {
+	reporter.record(service)
+}
+
+func (reporter *callRecorder) BeforeLoadConfiguration(service rscsrv.Configurable) {
+	reporter.record(service)
+}
+
+func (reporter *callRecorder) AfterLoadConfiguration(service rscsrv.Configurable, conf interface{}, err error) {
+	reporter.record(service)
+}
+
+func (reporter *callRecorder) BeforeApplyConfiguration(service rscsrv.Configurable) {
+	reporter.record(service)
+}
+
+func (reporter *callRecorder) AfterApplyConfiguration(service rscsrv.Configurable, conf interface{}, err error) {
+	reporter.record(service)
+}
+
+func (reporter *callRecorder) BeforeStart(service rscsrv.Service) {
+	reporter.record(service)
+}
+
+func (reporter *callRecorder) AfterStart(service rscsrv.Service, err error) {
+	reporter.record(service)
+}
+
+// wrappedReporter hides every optional interface of the reporter it wraps.
+type wrappedReporter struct {
+	rscsrv.ServiceStarterReporter
+}
+
+// configOnlyService is a service that is only `Configurable`.
+type configOnlyService struct {
+	name string
+}
+
+func (service *configOnlyService) Name() string {
+	return service.name
+}
+
+func (service *configOnlyService) LoadConfiguration() (interface{}, error) {
+	return nil, nil
+}
+
+func (service *configOnlyService) ApplyConfiguration(interface{}) error {
+	return nil
+}
+
+// eventRecorder is a `ServiceStarterEventReporter` that keeps all events.
+type eventRecorder struct {
+	countEngineReporter
+	events []rscsrv.LifecycleEvent
+}
+
+func (reporter *eventRecorder) ReportEvent(ctx context.Context, event rscsrv.LifecycleEvent) {
+	reporter.events = append(reporter.events, event)
+}
+
+func (reporter *eventRecorder) ReportRetrier(retrier *rscsrv.StartRetrier, err error) error {
+	return err
+}
+
+func (reporter *eventRecorder) Phases() []rscsrv.Phase {
+	phases := make([]rscsrv.Phase, len(reporter.events))
+	for i, event := range reporter.events {
+		phases[i] = event.Phase
+	}
+	return phases
+}
+
+type contextEventRecorder struct {
+	countEngineReporter
+	fnc func(ctx context.Context)
+}
+
+func (reporter *contextEventRecorder) ReportEvent(ctx context.Context, event rscsrv.LifecycleEvent) {
+	if event.Phase == rscsrv.PhaseStart {
+		reporter.fnc(ctx)
+	}
+}
+
+var _ = Describe("Parallel start reporting", func() {
+	It("should report the callbacks of each service together", func() {
+		reporter := &callRecorder{}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Reporter: &wrappedReporter{reporter},
+			Parallel: true,
+		},
+			&configOnlyService{name: "settings"},
+			&MockDependentService{name: "database", recorder: &orderRecorder{}, MockService: MockService{startDuration: time.Millisecond * 30}},
+			&MockDependentService{name: "cache", recorder: &orderRecorder{}, MockService: MockService{startDuration: time.Millisecond * 10}},
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		// 5 callbacks for the configuration of each service, and 2 for the
+		// start of the startable ones.
+		Expect(reporter.names).To(HaveLen(19))
+		var blocks []string
+		for i, name := range reporter.names {
+			if i == 0 || reporter.names[i-1] != name {
+				blocks = append(blocks, name)
+			}
+		}
+		Expect(blocks).To(ConsistOf("settings", "database", "cache"))
+
+		// The reporting goes on right away after the parallel start.
+		Expect(engineStarter.Stop(true)).To(Succeed())
+		Expect(reporter.names[len(reporter.names)-1]).To(Equal("settings"))
+	})
+})
+
+var _ = Describe("ServiceStarterEventReporter", func() {
+	It("should report an event for each phase instead of the After callbacks", func() {
+		reporter := &eventRecorder{}
+		service := &MockService{
+			startDuration: time.Millisecond * 20,
+			stopDuration:  time.Millisecond * 20,
+		}
+		engineStarter := rscsrv.NewServiceStarter(reporter, service)
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(engineStarter.Stop(false)).To(Succeed())
+
+		Expect(reporter.Phases()).To(Equal([]rscsrv.Phase{
+			rscsrv.PhaseLoadConfiguration,
+			rscsrv.PhaseApplyConfiguration,
+			rscsrv.PhaseStart,
+			rscsrv.PhaseStop,
+		}))
+		for _, event := range reporter.events {
+			Expect(event.Name).To(Equal("mock-service"))
+			Expect(event.Service).To(BeIdenticalTo(service))
+			Expect(event.Attempt).To(Equal(1))
+			Expect(event.StartedAt).ToNot(BeZero())
+			Expect(event.Err).ToNot(HaveOccurred())
+		}
+		Expect(reporter.events[2].Duration).To(BeNumerically(">=", time.Millisecond*20))
+		Expect(reporter.events[3].Duration).To(BeNumerically(">=", time.Millisecond*20))
+
+		Expect(reporter.countBeforeStart).To(Equal(1))
+		Expect(reporter.countBeforeStop).To(Equal(1))
+		Expect(reporter.countAfterLoadConfiguration).To(Equal(0))
+		Expect(reporter.countAfterApplyConfiguration).To(Equal(0))
+		Expect(reporter.countAfterStart).To(Equal(0))
+		Expect(reporter.countAfterStop).To(Equal(0))
+	})
+
+	It("should report the error of the phase", func() {
+		reporter := &eventRecorder{}
+		errStart := errors.New("start error")
+		engineStarter := rscsrv.NewServiceStarter(reporter, &MockService{errStart: errStart})
+		Expect(engineStarter.Start()).To(HaveOccurred())
+
+		Expect(reporter.events).To(HaveLen(3))
+		Expect(reporter.events[2].Phase).To(Equal(rscsrv.PhaseStart))
+		Expect(reporter.events[2].Err).To(Equal(errStart))
+	})
+
+	It("should pass the start context to the reporter", func() {
+		var reported interface{}
+		reporter := &contextEventRecorder{fnc: func(ctx context.Context) {
+			reported = ctx.Value(contextKey("request"))
+		}}
+		engineStarter := rscsrv.NewServiceStarter(reporter, &MockServiceWithCancellation{})
+		ctx := context.WithValue(context.Background(), contextKey("request"), "42")
+		Expect(engineStarter.StartWithContext(ctx)).To(Succeed())
+		Expect(reported).To(Equal("42"))
+	})
+
+	It("should report each attempt of a StartRetrier", func() {
+		reporter := &eventRecorder{}
+		service := &retrierMockService{successAt: 3}
+		retrier := rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{
+			DelayBetweenTries: time.Millisecond,
+			Reporter:          reporter,
+		})
+		Expect(retrier.(rscsrv.Startable).Start()).To(Succeed())
+
+		Expect(reporter.events).To(HaveLen(3))
+		for i, event := range reporter.events {
+			Expect(event.Phase).To(Equal(rscsrv.PhaseStartAttempt))
+			Expect(event.Name).To(Equal("retrierMockService"))
+			Expect(event.Attempt).To(Equal(i + 1))
+		}
+		Expect(reporter.events[0].Err).To(MatchError("failed to start"))
+		Expect(reporter.events[2].Err).ToNot(HaveOccurred())
+	})
+})
@@ -3,6 +3,8 @@ package rscsrv_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -78,6 +80,66 @@ type MockServiceWithCancellation struct {
 	cancel context.CancelFunc
 }
 
+// orderRecorder keeps track of the order services were started and stopped.
+type orderRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (recorder *orderRecorder) record(event string) {
+	recorder.mutex.Lock()
+	recorder.events = append(recorder.events, event)
+	recorder.mutex.Unlock()
+}
+
+func (recorder *orderRecorder) Events() []string {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return append([]string{}, recorder.events...)
+}
+
+type MockDependentService struct {
+	MockService
+	name      string
+	dependsOn []string
+	recorder  *orderRecorder
+}
+
+func (service *MockDependentService) Name() string {
+	return service.name
+}
+
+func (service *MockDependentService) DependsOn() []string {
+	return service.dependsOn
+}
+
+func (service *MockDependentService) Start() error {
+	service.recorder.record("start " + service.name)
+	return service.MockService.Start()
+}
+
+func (service *MockDependentService) Stop() error {
+	service.recorder.record("stop " + service.name)
+	return service.MockService.Stop()
+}
+
+type MockServiceWithStopContext struct {
+	MockDependentService
+	stopCtxErr error
+}
+
+func (service *MockServiceWithStopContext) StopWithContext(ctx context.Context) error {
+	service.recorder.record("stop " + service.name)
+	select {
+	case <-time.After(service.stopDuration):
+		service.stopped.Store(true)
+		return nil
+	case <-ctx.Done():
+		service.stopCtxErr = ctx.Err()
+		return ctx.Err()
+	}
+}
+
 func (service *MockService) Name() string {
 	return "mock-service"
 }
@@ -124,6 +186,31 @@ func (service *MockServiceWithCancellation) Stop() error {
 	return nil
 }
 
+type contextKey string
+
+// MockServiceWithContextValues records the value of the "request" key of the
+// contexts it receives.
+type MockServiceWithContextValues struct {
+	MockService
+	recorder *orderRecorder
+}
+
+func (service *MockServiceWithContextValues) LoadConfigurationWithContext(ctx context.Context) (interface{}, error) {
+	service.recorder.record(fmt.Sprintf("load %v", ctx.Value(contextKey("request"))))
+	return nil, nil
+}
+
+func (service *MockServiceWithContextValues) StartWithContext(ctx context.Context) error {
+	service.recorder.record(fmt.Sprintf("start %v", ctx.Value(contextKey("request"))))
+	return nil
+}
+
+func (service *MockServiceWithContextValues) Run(ctx context.Context) error {
+	service.recorder.record(fmt.Sprintf("run %v", ctx.Value(contextKey("request"))))
+	<-ctx.Done()
+	return nil
+}
+
 var _ = Describe("ServiceStarter", func() {
 	It("should start all service", func() {
 		reporter := &countEngineReporter{}
@@ -212,11 +299,41 @@ var _ = Describe("ServiceStarter", func() {
 		Expect(reporter.countAfterStop).To(Equal(0))
 
 		err = engineStarter.Stop(true)
-		Expect(err).ToNot(HaveOccurred())
+		Expect(err).To(HaveOccurred())
+		Expect(err).To(BeAssignableToTypeOf(rscsrv.MultiError{}))
+		Expect(err.(rscsrv.MultiError)).To(HaveLen(1))
+		Expect(err.Error()).To(ContainSubstring("stopping error"))
 		Expect(reporter.countBeforeStop).To(Equal(1))
 		Expect(reporter.countAfterStop).To(Equal(1))
 	})
 
+	It("should report every failure when stopping and keeping going", func() {
+		stopErr1 := errors.New("stopping error 1")
+		stopErr2 := errors.New("stopping error 2")
+		recorder := &orderRecorder{}
+		engineStarter := rscsrv.NewServiceStarter(
+			&rscsrv.NopStarterReporter{},
+			&MockDependentService{name: "service1", recorder: recorder, MockService: MockService{errStop: stopErr1}},
+			&MockDependentService{name: "service2", recorder: recorder},
+			&MockDependentService{name: "service3", recorder: recorder, MockService: MockService{errStop: stopErr2}},
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		err := engineStarter.Stop(true)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, stopErr1)).To(BeTrue())
+		Expect(errors.Is(err, stopErr2)).To(BeTrue())
+		Expect(err.(rscsrv.MultiError)).To(Equal(rscsrv.MultiError{
+			&rscsrv.ServiceError{Service: "service3", Phase: rscsrv.PhaseStop, Err: stopErr2},
+			&rscsrv.ServiceError{Service: "service1", Phase: rscsrv.PhaseStop, Err: stopErr1},
+		}))
+
+		var serviceErr *rscsrv.ServiceError
+		Expect(errors.As(err, &serviceErr)).To(BeTrue())
+		Expect(serviceErr.Service).To(Equal("service3"))
+		Expect(recorder.Events()).To(ContainElement("stop service2"))
+	})
+
 	It("should fail loading configuration", func() {
 		reporter := &countEngineReporter{}
 		engineStarter := rscsrv.NewServiceStarter(
@@ -228,6 +345,10 @@ var _ = Describe("ServiceStarter", func() {
 		err := engineStarter.Start()
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("loading configuration error"))
+		var serviceErr *rscsrv.ServiceError
+		Expect(errors.As(err, &serviceErr)).To(BeTrue())
+		Expect(serviceErr.Service).To(Equal("mock-service"))
+		Expect(serviceErr.Phase).To(Equal(rscsrv.PhaseLoadConfiguration))
 		Expect(reporter.countBeforeBegin).To(Equal(1))
 		Expect(reporter.countBeforeLoadConfiguration).To(Equal(1))
 		Expect(reporter.countAfterLoadConfiguration).To(Equal(1))
@@ -250,11 +371,14 @@ var _ = Describe("ServiceStarter", func() {
 		err := engineStarter.Start()
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("applying configuration error"))
+		var serviceErr *rscsrv.ServiceError
+		Expect(errors.As(err, &serviceErr)).To(BeTrue())
+		Expect(serviceErr.Phase).To(Equal(rscsrv.PhaseApplyConfiguration))
 		Expect(reporter.countBeforeBegin).To(Equal(1))
 		Expect(reporter.countBeforeLoadConfiguration).To(Equal(1))
 		Expect(reporter.countAfterLoadConfiguration).To(Equal(1))
 		Expect(reporter.countBeforeApplyConfiguration).To(Equal(1))
-		Expect(reporter.countAfterApplyConfiguration).To(Equal(0))
+		Expect(reporter.countAfterApplyConfiguration).To(Equal(1))
 		Expect(reporter.countBeforeStart).To(Equal(0))
 		Expect(reporter.countAfterStart).To(Equal(0))
 		Expect(reporter.countBeforeStop).To(Equal(0))
@@ -467,4 +591,285 @@ var _ = Describe("ServiceStarter", func() {
 		Expect(service2.stopped.Load()).To(BeTrue())
 		Expect(time.Since(startedAt).Seconds() * 1000).To(BeNumerically("~", 55, 10))
 	})
+
+	Context("Dependencies", func() {
+		It("should start services after their dependencies and stop them in reverse order", func() {
+			recorder := &orderRecorder{}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				&MockDependentService{name: "api", dependsOn: []string{"repository", "cache"}, recorder: recorder},
+				&MockDependentService{name: "repository", dependsOn: []string{"database"}, recorder: recorder},
+				&MockDependentService{name: "database", recorder: recorder},
+				&MockDependentService{name: "cache", recorder: recorder},
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(engineStarter.Stop(false)).To(Succeed())
+			Expect(recorder.Events()).To(Equal([]string{
+				"start database",
+				"start repository",
+				"start cache",
+				"start api",
+				"stop api",
+				"stop cache",
+				"stop repository",
+				"stop database",
+			}))
+		})
+
+		It("should keep the provided order for services without dependencies", func() {
+			recorder := &orderRecorder{}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				&MockDependentService{name: "service1", recorder: recorder},
+				&MockDependentService{name: "service2", recorder: recorder},
+				&MockDependentService{name: "service3", recorder: recorder},
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(engineStarter.Stop(false)).To(Succeed())
+			Expect(recorder.Events()).To(Equal([]string{
+				"start service1",
+				"start service2",
+				"start service3",
+				"stop service3",
+				"stop service2",
+				"stop service1",
+			}))
+		})
+
+		It("should fail when a dependency cycle is found", func() {
+			recorder := &orderRecorder{}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				&MockDependentService{name: "service1", dependsOn: []string{"service2"}, recorder: recorder},
+				&MockDependentService{name: "service2", dependsOn: []string{"service3"}, recorder: recorder},
+				&MockDependentService{name: "service3", dependsOn: []string{"service1"}, recorder: recorder},
+			)
+			err := engineStarter.Start()
+			Expect(err).To(BeAssignableToTypeOf(&rscsrv.DependencyCycleError{}))
+			Expect(err.(*rscsrv.DependencyCycleError).Cycle).To(Equal([]string{"service1", "service2", "service3", "service1"}))
+			Expect(err.Error()).To(Equal("dependency cycle detected: service1 -> service2 -> service3 -> service1"))
+			Expect(recorder.Events()).To(BeEmpty())
+		})
+
+		It("should fail when a dependency is not provided", func() {
+			recorder := &orderRecorder{}
+			engineStarter := rscsrv.NewServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				&MockDependentService{name: "service1", dependsOn: []string{"unknown"}, recorder: recorder},
+			)
+			err := engineStarter.Start()
+			Expect(err).To(Equal(&rscsrv.DependencyNotFoundError{
+				Service:    "service1",
+				Dependency: "unknown",
+			}))
+			Expect(recorder.Events()).To(BeEmpty())
+		})
+	})
+
+	Context("Parallel", func() {
+		It("should start independent services concurrently", func() {
+			recorder := &orderRecorder{}
+			engineStarter := rscsrv.ParallelServiceStarter(
+				&rscsrv.NopStarterReporter{},
+				&MockDependentService{name: "service1", recorder: recorder, MockService: MockService{startDuration: time.Millisecond * 100}},
+				&MockDependentService{name: "service2", recorder: recorder, MockService: MockService{startDuration: time.Millisecond * 100}},
+				&MockDependentService{name: "service3", recorder: recorder, MockService: MockService{startDuration: time.Millisecond * 100}},
+			)
+			startedAt := time.Now()
+			Expect(engineStarter.Start()).To(Succeed())
+			Expect(time.Since(startedAt)).To(BeNumerically("<", time.Millisecond*200))
+			Expect(recorder.Events()).To(ConsistOf("start service1", "start service2", "start service3"))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+
+		It("should start a service only after its dependencies", func() {
+			recorder := &orderRecorder{}
+			reporter := &countEngineReporter{}
+			engineStarter := rscsrv.ParallelServiceStarter(
+				reporter,
+				&MockDependentService{name: "api", dependsOn: []string{"database", "cache"}, recorder: recorder},
+				&MockDependentService{name: "database", recorder: recorder, MockService: MockService{startDuration: time.Millisecond * 50}},
+				&MockDependentService{name: "cache", recorder: recorder},
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			events := recorder.Events()
+			Expect(events).To(HaveLen(3))
+			Expect(events[2]).To(Equal("start api"))
+			Expect(reporter.countBeforeBegin).To(Equal(3))
+			Expect(reporter.countAfterStart).To(Equal(3))
+
+			Expect(engineStarter.Stop(false)).To(Succeed())
+			Expect(recorder.Events()[3]).To(Equal("stop api"))
+		})
+
+		It("should cancel the other starts and stop started services on the first failure", func() {
+			recorder := &orderRecorder{}
+			reporter := &countEngineReporter{}
+			started := &MockDependentService{name: "started", recorder: recorder}
+			cancellable := &MockServiceWithCancellation{
+				MockService: MockService{
+					startDuration: time.Second,
+				},
+			}
+			dependent := &MockDependentService{name: "dependent", dependsOn: []string{"failing"}, recorder: recorder}
+			engineStarter := rscsrv.ParallelServiceStarter(
+				reporter,
+				started,
+				cancellable,
+				&MockDependentService{
+					name:     "failing",
+					recorder: recorder,
+					MockService: MockService{
+						startDuration: time.Millisecond * 50,
+						errStart:      errors.New("start error"),
+					},
+				},
+				dependent,
+			)
+			startedAt := time.Now()
+			err := engineStarter.Start()
+			Expect(err).To(MatchError("failing: start: start error"))
+			Expect(time.Since(startedAt)).To(BeNumerically("<", time.Millisecond*500))
+			Expect(started.stopped.Load()).To(BeTrue())
+			Expect(dependent.started.Load()).To(BeFalse())
+			Expect(reporter.countBeforeStart).To(Equal(3))
+			Expect(reporter.countAfterStart).To(Equal(3))
+			Expect(reporter.countBeforeStop).To(Equal(1))
+		})
+	})
+
+	Context("Stop deadlines", func() {
+		It("should cancel the context of a service that exceeds its stop timeout", func() {
+			recorder := &orderRecorder{}
+			service := &MockServiceWithStopContext{
+				MockDependentService: MockDependentService{
+					name:        "service1",
+					recorder:    recorder,
+					MockService: MockService{stopDuration: time.Second},
+				},
+			}
+			engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+				ServiceStopTimeouts: map[string]time.Duration{
+					"service1": time.Millisecond * 50,
+				},
+			}, service)
+			Expect(engineStarter.Start()).To(Succeed())
+			startedAt := time.Now()
+			err := engineStarter.Stop(false)
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+			Expect(err.(*rscsrv.ServiceError).Phase).To(Equal(rscsrv.PhaseStop))
+			Expect(time.Since(startedAt)).To(BeNumerically("<", time.Millisecond*500))
+			Expect(service.stopCtxErr).To(Equal(context.DeadlineExceeded))
+		})
+
+		It("should abandon a hanging service and keep stopping the others", func() {
+			recorder := &orderRecorder{}
+			service1 := &MockDependentService{name: "service1", recorder: recorder}
+			service2 := &MockDependentService{
+				name:        "service2",
+				recorder:    recorder,
+				MockService: MockService{stopDuration: time.Second},
+			}
+			reporter := &countEngineReporter{}
+			engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+				Reporter:           reporter,
+				ServiceStopTimeout: time.Millisecond * 50,
+			}, service1, service2)
+			Expect(engineStarter.Start()).To(Succeed())
+			startedAt := time.Now()
+			err := engineStarter.Stop(true)
+			Expect(time.Since(startedAt)).To(BeNumerically("<", time.Millisecond*500))
+			Expect(errors.Is(err, rscsrv.ErrStopTimeout)).To(BeTrue())
+			Expect(service1.stopped.Load()).To(BeTrue())
+			Expect(reporter.countAfterStop).To(Equal(2))
+		})
+
+		It("should give up the shutdown when the global deadline is reached", func() {
+			recorder := &orderRecorder{}
+			engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+				StopTimeout: time.Millisecond * 150,
+			},
+				&MockDependentService{name: "service1", recorder: recorder, MockService: MockService{stopDuration: time.Millisecond * 100}},
+				&MockDependentService{name: "service2", recorder: recorder, MockService: MockService{stopDuration: time.Millisecond * 100}},
+				&MockDependentService{name: "service3", recorder: recorder, MockService: MockService{stopDuration: time.Millisecond * 100}},
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			startedAt := time.Now()
+			err := engineStarter.Stop(true)
+			Expect(err).To(Equal(rscsrv.MultiError{
+				&rscsrv.ServiceError{Service: "service2", Phase: rscsrv.PhaseStop, Err: rscsrv.ErrStopTimeout},
+				rscsrv.ErrStopTimeout,
+			}))
+			Expect(time.Since(startedAt)).To(BeNumerically("<", time.Millisecond*250))
+			Expect(recorder.Events()).To(Equal([]string{
+				"start service1",
+				"start service2",
+				"start service3",
+				"stop service3",
+				"stop service2",
+			}))
+		})
+
+		It("should stop services without dependents concurrently", func() {
+			recorder := &orderRecorder{}
+			engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+				ParallelStop: true,
+			},
+				&MockDependentService{name: "database", recorder: recorder, MockService: MockService{stopDuration: time.Millisecond * 100}},
+				&MockDependentService{name: "api", dependsOn: []string{"database"}, recorder: recorder, MockService: MockService{stopDuration: time.Millisecond * 100}},
+				&MockDependentService{name: "worker", dependsOn: []string{"database"}, recorder: recorder, MockService: MockService{stopDuration: time.Millisecond * 100}},
+			)
+			Expect(engineStarter.Start()).To(Succeed())
+			startedAt := time.Now()
+			Expect(engineStarter.Stop(false)).To(Succeed())
+			Expect(time.Since(startedAt)).To(BeNumerically("<", time.Millisecond*300))
+			events := recorder.Events()[3:]
+			Expect(events[:2]).To(ConsistOf("stop api", "stop worker"))
+			Expect(events[2]).To(Equal("stop database"))
+		})
+	})
+
+	Context("Start context", func() {
+		It("should pass the context down to the services", func() {
+			recorder := &orderRecorder{}
+			service := &MockServiceWithContextValues{recorder: recorder}
+			engineStarter := rscsrv.QuietServiceStarter(service)
+			ctx := context.WithValue(context.Background(), contextKey("request"), "42")
+			Expect(engineStarter.StartWithContext(ctx)).To(Succeed())
+			Eventually(recorder.Events).Should(Equal([]string{
+				"load 42",
+				"start 42",
+				"run 42",
+			}))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+
+		It("should cancel the start when the context is cancelled", func() {
+			service1 := &MockServiceWithCancellation{}
+			service1.startDuration = time.Second
+			service2 := &MockService{}
+			engineStarter := rscsrv.QuietServiceStarter(service1, service2)
+			ctx, cancelFunc := context.WithTimeout(context.Background(), time.Millisecond*25)
+			defer cancelFunc()
+
+			Expect(engineStarter.StartWithContext(ctx)).To(Equal(context.DeadlineExceeded))
+			Expect(service2.started.Load()).To(BeFalse())
+			Expect(engineStarter.Stop(true)).To(Succeed())
+		})
+
+		It("should cancel the start when the start timeout is reached", func() {
+			service1 := &MockServiceWithCancellation{}
+			service1.startDuration = time.Second
+			service2 := &MockService{}
+			engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+				StartTimeout: time.Millisecond * 25,
+			}, service1, service2)
+
+			startedAt := time.Now()
+			Expect(engineStarter.Start()).To(Equal(context.DeadlineExceeded))
+			Expect(time.Since(startedAt)).To(BeNumerically("<", time.Millisecond*500))
+			Expect(service2.started.Load()).To(BeFalse())
+			Expect(engineStarter.Stop(true)).To(Succeed())
+		})
+	})
 })
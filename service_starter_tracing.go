@@ -0,0 +1,255 @@
+package rscsrv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero returns true for the parent of root spans.
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+// Span is a timed operation of the lifecycle of the services: the start, or
+// stop, of all services, of a service, or a phase of a service.
+type Span struct {
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID
+	Name         string
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]string
+	// Err is the error of the operation, if it failed.
+	Err error
+}
+
+// SpanExporter is the abstraction that receives the spans finished by the
+// `TracingStarterReporter`.
+type SpanExporter interface {
+	// ExportSpans exports the spans of a trace.
+	ExportSpans(ctx context.Context, spans []Span) error
+}
+
+// TracingStarterReporter is a reporter that builds a trace for each start,
+// and stop, of all services: a root span for the sequence, a child span for
+// each service and, under it, a span for each phase (load configuration,
+// apply configuration, start, stop). The attempts of a `StartRetrier` that
+// reports to it become children of the start span.
+//
+// The spans of a trace are exported when the sequence ends. Phases that
+// happen outside of a sequence (eg: reloads and restarts) are exported as
+// traces of a single span. The same reporter can be shared by many
+// `ServiceStarter`s, since each sequence is identified by its context.
+type TracingStarterReporter struct {
+	exporter SpanExporter
+
+	mutex  sync.Mutex
+	traces map[*sequenceRun]*sequenceTrace
+	begins map[string]time.Time
+}
+
+// sequenceTrace keeps the spans of a sequence until it ends.
+type sequenceTrace struct {
+	root     Span
+	services map[string]*Span
+	attempts map[string][]Span
+	spans    []Span
+}
+
+// NewTracingStarterReporter returns a new `TracingStarterReporter` that
+// exports the spans to the exporter.
+func NewTracingStarterReporter(exporter SpanExporter) *TracingStarterReporter {
+	return &TracingStarterReporter{
+		exporter: exporter,
+		traces:   make(map[*sequenceRun]*sequenceTrace),
+		begins:   make(map[string]time.Time),
+	}
+}
+
+func newTraceID() (id TraceID) {
+	_, _ = rand.Read(id[:])
+	return
+}
+
+func newSpanID() (id SpanID) {
+	_, _ = rand.Read(id[:])
+	return
+}
+
+// BeginSequence starts the root span of a new trace.
+func (reporter *TracingStarterReporter) BeginSequence(ctx context.Context, sequence Sequence) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.traces[sequenceOf(ctx)] = &sequenceTrace{
+		root: Span{
+			TraceID:   newTraceID(),
+			SpanID:    newSpanID(),
+			Name:      string(sequence),
+			StartTime: time.Now(),
+			Attributes: map[string]string{
+				"rscsrv.sequence": string(sequence),
+			},
+		},
+		services: make(map[string]*Span),
+		attempts: make(map[string][]Span),
+	}
+}
+
+// EndSequence finishes the trace of the sequence and exports its spans.
+func (reporter *TracingStarterReporter) EndSequence(ctx context.Context, sequence Sequence, err error) {
+	reporter.mutex.Lock()
+	run := sequenceOf(ctx)
+	trace, ok := reporter.traces[run]
+	delete(reporter.traces, run)
+	reporter.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	for _, span := range trace.services {
+		// Services with nothing to start, or stop, have no final phase.
+		span.EndTime = now
+		trace.spans = append(trace.spans, *span)
+	}
+	trace.root.EndTime = now
+	trace.root.Err = err
+	reporter.export(ctx, append(trace.spans, trace.root))
+}
+
+// export sends the spans to the exporter. Errors are ignored, since there is
+// nowhere else to report them.
+func (reporter *TracingStarterReporter) export(ctx context.Context, spans []Span) {
+	_ = reporter.exporter.ExportSpans(ctx, spans)
+}
+
+// ReportEvent creates the span of the phase.
+func (reporter *TracingStarterReporter) ReportEvent(ctx context.Context, event LifecycleEvent) {
+	span := Span{
+		SpanID:    newSpanID(),
+		Name:      string(event.Phase),
+		StartTime: event.StartedAt,
+		EndTime:   event.StartedAt.Add(event.Duration),
+		Attributes: map[string]string{
+			"rscsrv.service": event.Name,
+			"rscsrv.phase":   string(event.Phase),
+		},
+		Err: event.Err,
+	}
+	if event.Phase == PhaseStartAttempt {
+		span.Attributes["rscsrv.attempt"] = strconv.Itoa(event.Attempt)
+	}
+
+	reporter.mutex.Lock()
+	trace, ok := reporter.traces[sequenceOf(ctx)]
+	if !ok {
+		delete(reporter.begins, event.Name)
+		reporter.mutex.Unlock()
+		span.TraceID = newTraceID()
+		reporter.export(ctx, []Span{span})
+		return
+	}
+	defer reporter.mutex.Unlock()
+
+	span.TraceID = trace.root.TraceID
+	if event.Phase == PhaseStartAttempt {
+		// The start span, their parent, is only known when the start finishes.
+		trace.attempts[event.Name] = append(trace.attempts[event.Name], span)
+		return
+	}
+
+	service, ok := trace.services[event.Name]
+	if !ok {
+		startTime, ok := reporter.begins[event.Name]
+		if !ok || startTime.After(event.StartedAt) {
+			startTime = event.StartedAt
+		}
+		delete(reporter.begins, event.Name)
+		service = &Span{
+			TraceID:      trace.root.TraceID,
+			SpanID:       newSpanID(),
+			ParentSpanID: trace.root.SpanID,
+			Name:         event.Name,
+			StartTime:    startTime,
+			Attributes: map[string]string{
+				"rscsrv.service": event.Name,
+			},
+		}
+		trace.services[event.Name] = service
+	}
+	span.ParentSpanID = service.SpanID
+	trace.spans = append(trace.spans, span)
+
+	if event.Phase == PhaseStart {
+		for _, attempt := range trace.attempts[event.Name] {
+			attempt.ParentSpanID = span.SpanID
+			trace.spans = append(trace.spans, attempt)
+		}
+		delete(trace.attempts, event.Name)
+	}
+
+	// The service span finishes with its last phase, or the first failure.
+	if event.Err != nil || event.Phase == PhaseStart || event.Phase == PhaseStop {
+		service.EndTime = span.EndTime
+		service.Err = event.Err
+		trace.spans = append(trace.spans, *service)
+		delete(trace.services, event.Name)
+	}
+}
+
+// BeforeBegin records when the service began, which is the start of its
+// span.
+func (reporter *TracingStarterReporter) BeforeBegin(service Service) {
+	reporter.mutex.Lock()
+	reporter.begins[service.Name()] = time.Now()
+	reporter.mutex.Unlock()
+}
+
+func (*TracingStarterReporter) BeforeLoadConfiguration(service Configurable) {}
+
+// AfterLoadConfiguration is not called, since the spans are created by
+// `ReportEvent`.
+func (*TracingStarterReporter) AfterLoadConfiguration(service Configurable, conf interface{}, err error) {
+}
+
+func (*TracingStarterReporter) BeforeApplyConfiguration(service Configurable) {}
+
+// AfterApplyConfiguration is not called, since the spans are created by
+// `ReportEvent`.
+func (*TracingStarterReporter) AfterApplyConfiguration(service Configurable, conf interface{}, err error) {
+}
+
+func (*TracingStarterReporter) BeforeStart(service Service) {}
+
+// AfterStart is not called, since the spans are created by `ReportEvent`.
+func (*TracingStarterReporter) AfterStart(service Service, err error) {}
+
+func (*TracingStarterReporter) BeforeStop(service Service) {}
+
+// AfterStop is not called, since the spans are created by `ReportEvent`.
+func (*TracingStarterReporter) AfterStop(service Service, err error) {}
+
+// ReportRetrier returns err untouched. The attempts are traced by
+// `ReportEvent`.
+func (*TracingStarterReporter) ReportRetrier(retrier *StartRetrier, err error) error {
+	return err
+}
@@ -0,0 +1,205 @@
+package rscsrv_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// spanRecorder is a `SpanExporter` that keeps the exported traces.
+type spanRecorder struct {
+	mutex  sync.Mutex
+	traces [][]rscsrv.Span
+}
+
+func (exporter *spanRecorder) ExportSpans(ctx context.Context, spans []rscsrv.Span) error {
+	exporter.mutex.Lock()
+	exporter.traces = append(exporter.traces, spans)
+	exporter.mutex.Unlock()
+	return nil
+}
+
+// findSpan returns the span with the given name and the given parent.
+func findSpan(spans []rscsrv.Span, name string, parent rscsrv.SpanID) rscsrv.Span {
+	for _, span := range spans {
+		if span.Name == name && span.ParentSpanID == parent {
+			return span
+		}
+	}
+	Fail("span " + name + " not found")
+	return rscsrv.Span{}
+}
+
+var _ = Describe("TracingStarterReporter", func() {
+	It("should trace the start and the stop of the services", func() {
+		exporter := &spanRecorder{}
+		reporter := rscsrv.NewTracingStarterReporter(exporter)
+		engineStarter := rscsrv.NewServiceStarter(reporter,
+			&MockDependentService{name: "database", recorder: &orderRecorder{}},
+			rscsrv.NewStartRetrier(&retrierMockService{successAt: 2}, rscsrv.StartRetrierOptions{
+				DelayBetweenTries: time.Millisecond,
+				Reporter:          reporter,
+			}),
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		Expect(exporter.traces).To(HaveLen(2))
+
+		spans := exporter.traces[0]
+		Expect(spans).To(HaveLen(11))
+		root := findSpan(spans, "start", rscsrv.SpanID{})
+		Expect(root.EndTime).To(BeTemporally(">=", root.StartTime))
+		for _, span := range spans {
+			Expect(span.TraceID).To(Equal(root.TraceID))
+		}
+
+		database := findSpan(spans, "database", root.SpanID)
+		findSpan(spans, "load configuration", database.SpanID)
+		findSpan(spans, "apply configuration", database.SpanID)
+		findSpan(spans, "start", database.SpanID)
+
+		retrier := findSpan(spans, "retrierMockService", root.SpanID)
+		start := findSpan(spans, "start", retrier.SpanID)
+		attempt := findSpan(spans, "start attempt", start.SpanID)
+		Expect(attempt.Attributes).To(HaveKeyWithValue("rscsrv.service", "retrierMockService"))
+		Expect(attempt.Attributes).To(HaveKey("rscsrv.attempt"))
+		Expect(retrier.StartTime).To(BeTemporally("<=", start.StartTime))
+		Expect(retrier.EndTime).To(Equal(start.EndTime))
+
+		spans = exporter.traces[1]
+		root = findSpan(spans, "stop", rscsrv.SpanID{})
+		database = findSpan(spans, "database", root.SpanID)
+		findSpan(spans, "stop", database.SpanID)
+	})
+
+	It("should keep apart the traces of starters sharing the reporter", func() {
+		exporter := &spanRecorder{}
+		reporter := rscsrv.NewTracingStarterReporter(exporter)
+		starters := []rscsrv.ServiceStarter{
+			rscsrv.NewServiceStarter(reporter, &MockDependentService{name: "database", recorder: &orderRecorder{}, MockService: MockService{startDuration: time.Millisecond * 20}}),
+			rscsrv.NewServiceStarter(reporter, &MockDependentService{name: "cache", recorder: &orderRecorder{}, MockService: MockService{startDuration: time.Millisecond * 20}}),
+		}
+		var wg sync.WaitGroup
+		for _, engineStarter := range starters {
+			wg.Add(1)
+			go func(engineStarter rscsrv.ServiceStarter) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(engineStarter.Start()).To(Succeed())
+			}(engineStarter)
+		}
+		wg.Wait()
+
+		exporter.mutex.Lock()
+		defer exporter.mutex.Unlock()
+		Expect(exporter.traces).To(HaveLen(2))
+		var names []string
+		for _, spans := range exporter.traces {
+			root := findSpan(spans, "start", rscsrv.SpanID{})
+			Expect(spans).To(HaveLen(5))
+			for _, span := range spans {
+				Expect(span.TraceID).To(Equal(root.TraceID))
+				if span.ParentSpanID == root.SpanID {
+					names = append(names, span.Name)
+				}
+			}
+		}
+		Expect(names).To(ConsistOf("database", "cache"))
+	})
+
+	It("should record the errors in the spans", func() {
+		exporter := &spanRecorder{}
+		engineStarter := rscsrv.NewServiceStarter(rscsrv.NewTracingStarterReporter(exporter),
+			&MockDependentService{name: "database", recorder: &orderRecorder{}, MockService: MockService{errStart: errors.New("start error")}},
+		)
+		Expect(engineStarter.Start()).To(HaveOccurred())
+
+		spans := exporter.traces[0]
+		root := findSpan(spans, "start", rscsrv.SpanID{})
+		Expect(root.Err).To(MatchError("database: start: start error"))
+		database := findSpan(spans, "database", root.SpanID)
+		Expect(database.Err).To(MatchError("start error"))
+		Expect(findSpan(spans, "start", database.SpanID).Err).To(MatchError("start error"))
+	})
+})
+
+var _ = Describe("OTLPFileExporter", func() {
+	spans := []rscsrv.Span{
+		{
+			TraceID:   rscsrv.TraceID{1},
+			SpanID:    rscsrv.SpanID{2},
+			Name:      "start",
+			StartTime: time.Unix(1, 0),
+			EndTime:   time.Unix(2, 0),
+		},
+		{
+			TraceID:      rscsrv.TraceID{1},
+			SpanID:       rscsrv.SpanID{3},
+			ParentSpanID: rscsrv.SpanID{2},
+			Name:         "database",
+			StartTime:    time.Unix(1, 0),
+			EndTime:      time.Unix(1, 500),
+			Attributes:   map[string]string{"rscsrv.service": "database"},
+			Err:          errors.New("start error"),
+		},
+	}
+
+	It("should write the spans as OTLP JSON", func() {
+		var buf bytes.Buffer
+		exporter := rscsrv.NewOTLPWriterExporter(&buf, "api")
+		Expect(exporter.ExportSpans(context.Background(), spans)).To(Succeed())
+		Expect(buf.String()).To(HaveSuffix("\n"))
+
+		var request map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &request)).To(Succeed())
+		resourceSpans := request["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		Expect(resourceSpans["resource"]).To(Equal(map[string]interface{}{
+			"attributes": []interface{}{
+				map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "api"}},
+			},
+		}))
+		otlpSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+		Expect(otlpSpans).To(HaveLen(2))
+		Expect(otlpSpans[0]).To(Equal(map[string]interface{}{
+			"traceId":           "01000000000000000000000000000000",
+			"spanId":            "0200000000000000",
+			"name":              "start",
+			"kind":              float64(1),
+			"startTimeUnixNano": "1000000000",
+			"endTimeUnixNano":   "2000000000",
+			"status":            map[string]interface{}{"code": float64(0)},
+		}))
+		Expect(otlpSpans[1]).To(HaveKeyWithValue("parentSpanId", "0200000000000000"))
+		Expect(otlpSpans[1]).To(HaveKeyWithValue("endTimeUnixNano", "1000000500"))
+		Expect(otlpSpans[1]).To(HaveKeyWithValue("status", map[string]interface{}{"code": float64(2), "message": "start error"}))
+	})
+
+	It("should append the spans to a file", func() {
+		dir, err := ioutil.TempDir("", "rscsrv")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		filename := filepath.Join(dir, "traces.json")
+		exporter, err := rscsrv.NewOTLPFileExporter(filename, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exporter.ExportSpans(context.Background(), spans)).To(Succeed())
+		Expect(exporter.ExportSpans(context.Background(), spans)).To(Succeed())
+		Expect(exporter.Close()).To(Succeed())
+
+		data, err := ioutil.ReadFile(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bytes.Count(data, []byte("\n"))).To(Equal(2))
+		Expect(string(data)).To(ContainSubstring(`"stringValue":"rscsrv"`))
+	})
+})
@@ -0,0 +1,169 @@
+package rscsrv
+
+import (
+	"sync"
+	"time"
+)
+
+// ServiceState is the lifecycle state of a service managed by a
+// `ServiceStarter`.
+type ServiceState string
+
+const (
+	// StatePending is the state of a service that was not started yet.
+	StatePending ServiceState = "pending"
+	// StateConfiguring is the state of a service that is loading or applying
+	// its configuration.
+	StateConfiguring ServiceState = "configuring"
+	// StateStarting is the state of a service that is starting.
+	StateStarting ServiceState = "starting"
+	// StateStartingInBackground is the state of a service that keeps
+	// starting in background, while the other services go on (see
+	// `BackgroundStartable`).
+	StateStartingInBackground ServiceState = "starting in background"
+	// StateRunning is the state of a service successfully started.
+	StateRunning ServiceState = "running"
+	// StateStopping is the state of a service that is stopping.
+	StateStopping ServiceState = "stopping"
+	// StateStopped is the state of a service that was stopped, or that had
+	// its start cancelled.
+	StateStopped ServiceState = "stopped"
+	// StateFailed is the state of a service that failed to be configured,
+	// started or stopped.
+	StateFailed ServiceState = "failed"
+)
+
+// ServiceSnapshot is the state of a service at a given moment.
+type ServiceSnapshot struct {
+	// Name is the name of the service.
+	Name string `json:"name"`
+	// State is the current state of the service.
+	State ServiceState `json:"state"`
+	// Critical tells whether the application can work without this service.
+	// See `Critical`.
+	Critical bool `json:"critical"`
+	// Since is when the service entered the current state.
+	Since time.Time `json:"since"`
+	// StartedAt is when the service was last started, if ever.
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	// StoppedAt is when the service was last stopped, if ever.
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	// LastError is the message of the last error reported by the service.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Snapshot is the state of all services of a `ServiceStarter` at a given
+// moment.
+type Snapshot struct {
+	// Time is when the snapshot was taken.
+	Time time.Time `json:"time"`
+	// Services lists the state of each service, in the order they were
+	// provided to the `ServiceStarter`.
+	Services []ServiceSnapshot `json:"services"`
+}
+
+// ServiceStateReporter is an optional interface for
+// `ServiceStarterReporter`s that are notified whenever a service moves to
+// another state, as returned by `ServiceStarter.State`.
+type ServiceStateReporter interface {
+	// ReportState is called with the new state of the service and, if it
+	// failed, the error. It must not call back the `ServiceStarter`.
+	ReportState(service Service, state ServiceState, err error)
+}
+
+// serviceStatus keeps track of the state of a service.
+type serviceStatus struct {
+	mutex     sync.RWMutex
+	name      string
+	critical  bool
+	state     ServiceState
+	since     time.Time
+	startedAt time.Time
+	stoppedAt time.Time
+	lastErr   error
+
+	// onChange, if set, is called, with the mutex locked, whenever the state
+	// changes.
+	onChange func(state ServiceState, err error)
+}
+
+func newServiceStatus(srv Service) *serviceStatus {
+	status := &serviceStatus{
+		name:     srv.Name(),
+		critical: true,
+		state:    StatePending,
+		since:    time.Now(),
+	}
+	var critical Critical
+	if As(srv, &critical) {
+		status.critical = critical.Critical()
+	}
+	return status
+}
+
+// set moves the service to the given state. If err is not nil, it is kept as
+// the last error of the service.
+func (status *serviceStatus) set(state ServiceState, err error) {
+	status.mutex.Lock()
+	defer status.mutex.Unlock()
+	status.setLocked(state, err)
+}
+
+// setIf moves the service to the given state only if it is in the expected
+// one. It returns whether the state was changed.
+func (status *serviceStatus) setIf(expected, state ServiceState, err error) bool {
+	status.mutex.Lock()
+	defer status.mutex.Unlock()
+	if status.state != expected {
+		return false
+	}
+	status.setLocked(state, err)
+	return true
+}
+
+func (status *serviceStatus) setLocked(state ServiceState, err error) {
+	status.state = state
+	status.since = time.Now()
+	switch state {
+	case StateRunning:
+		status.startedAt = status.since
+	case StateStopped:
+		status.stoppedAt = status.since
+	}
+	if err != nil {
+		status.lastErr = err
+	}
+	if status.onChange != nil {
+		status.onChange(state, err)
+	}
+}
+
+func (status *serviceStatus) State() ServiceState {
+	status.mutex.RLock()
+	defer status.mutex.RUnlock()
+	return status.state
+}
+
+func (status *serviceStatus) Snapshot() ServiceSnapshot {
+	status.mutex.RLock()
+	defer status.mutex.RUnlock()
+
+	snapshot := ServiceSnapshot{
+		Name:     status.name,
+		State:    status.state,
+		Critical: status.critical,
+		Since:    status.since,
+	}
+	if !status.startedAt.IsZero() {
+		startedAt := status.startedAt
+		snapshot.StartedAt = &startedAt
+	}
+	if !status.stoppedAt.IsZero() {
+		stoppedAt := status.stoppedAt
+		snapshot.StoppedAt = &stoppedAt
+	}
+	if status.lastErr != nil {
+		snapshot.LastError = status.lastErr.Error()
+	}
+	return snapshot
+}
@@ -0,0 +1,112 @@
+package rscsrv_test
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// stateOf returns the state of the service, ignoring whether it exists.
+func stateOf(starter rscsrv.ServiceStarter, name string) rscsrv.ServiceState {
+	state, _ := starter.State(name)
+	return state
+}
+
+var _ = Describe("ServiceState", func() {
+	It("should list the services in the order they were provided", func() {
+		recorder := &orderRecorder{}
+		engineStarter := rscsrv.QuietServiceStarter(
+			&MockDependentService{name: "api", dependsOn: []string{"database"}, recorder: recorder},
+			&MockDependentService{name: "database", recorder: recorder},
+		)
+		Expect(engineStarter.Services()).To(Equal([]string{"api", "database"}))
+	})
+
+	It("should track the state of the services through their lifecycle", func() {
+		recorder := &orderRecorder{}
+		engineStarter := rscsrv.QuietServiceStarter(
+			&MockDependentService{name: "service1", recorder: recorder},
+			&MockDependentService{name: "service2", recorder: recorder, MockService: MockService{startDuration: time.Millisecond * 100}},
+		)
+		Expect(stateOf(engineStarter, "service1")).To(Equal(rscsrv.StatePending))
+
+		go func() {
+			defer GinkgoRecover()
+			Eventually(func() rscsrv.ServiceState {
+				return stateOf(engineStarter, "service2")
+			}).Should(Equal(rscsrv.StateStarting))
+			Expect(stateOf(engineStarter, "service1")).To(Equal(rscsrv.StateRunning))
+		}()
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(stateOf(engineStarter, "service1")).To(Equal(rscsrv.StateRunning))
+		Expect(stateOf(engineStarter, "service2")).To(Equal(rscsrv.StateRunning))
+
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		Expect(stateOf(engineStarter, "service1")).To(Equal(rscsrv.StateStopped))
+		Expect(stateOf(engineStarter, "service2")).To(Equal(rscsrv.StateStopped))
+	})
+
+	It("should stop the services that have nothing to start", func() {
+		engineStarter := rscsrv.QuietServiceStarter(&retrierMockServiceNonStartable{})
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(stateOf(engineStarter, "retrierMockServiceNonStartable")).To(Equal(rscsrv.StateRunning))
+
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		Expect(stateOf(engineStarter, "retrierMockServiceNonStartable")).To(Equal(rscsrv.StateStopped))
+		Expect(engineStarter.Snapshot().Services[0].StoppedAt).ToNot(BeNil())
+	})
+
+	It("should report unknown services", func() {
+		engineStarter := rscsrv.QuietServiceStarter(&MockService{})
+		_, ok := engineStarter.State("unknown")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should keep the last error of a failed service", func() {
+		recorder := &orderRecorder{}
+		engineStarter := rscsrv.QuietServiceStarter(
+			&MockDependentService{name: "service1", recorder: recorder},
+			&MockDependentService{name: "service2", recorder: recorder, MockService: MockService{errStart: errors.New("start error")}},
+			&MockDependentService{name: "service3", recorder: recorder},
+		)
+		Expect(engineStarter.Start()).ToNot(Succeed())
+
+		snapshot := engineStarter.Snapshot()
+		Expect(snapshot.Services).To(HaveLen(3))
+		Expect(snapshot.Services[0].State).To(Equal(rscsrv.StateRunning))
+		Expect(snapshot.Services[0].StartedAt).ToNot(BeNil())
+		Expect(snapshot.Services[1].State).To(Equal(rscsrv.StateFailed))
+		Expect(snapshot.Services[1].LastError).To(Equal("start error"))
+		Expect(snapshot.Services[2].State).To(Equal(rscsrv.StatePending))
+	})
+
+	It("should serialize the snapshot as JSON", func() {
+		recorder := &orderRecorder{}
+		engineStarter := rscsrv.QuietServiceStarter(
+			&MockDependentService{name: "service1", recorder: recorder},
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(engineStarter.Stop(false)).To(Succeed())
+
+		data, err := json.Marshal(engineStarter.Snapshot())
+		Expect(err).ToNot(HaveOccurred())
+
+		var snapshot map[string]interface{}
+		Expect(json.Unmarshal(data, &snapshot)).To(Succeed())
+		Expect(snapshot).To(HaveKey("time"))
+		services := snapshot["services"].([]interface{})
+		Expect(services).To(HaveLen(1))
+		service := services[0].(map[string]interface{})
+		Expect(service["name"]).To(Equal("service1"))
+		Expect(service["state"]).To(Equal("stopped"))
+		Expect(service).To(HaveKey("since"))
+		Expect(service).To(HaveKey("started_at"))
+		Expect(service).To(HaveKey("stopped_at"))
+		Expect(service).ToNot(HaveKey("last_error"))
+	})
+})
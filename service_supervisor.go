@@ -0,0 +1,362 @@
+package rscsrv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrRestartBudgetExceeded is the error reported when a `Runnable`
+	// service is restarted more than `SupervisorOptions.MaxRestarts` times
+	// within the `SupervisorOptions.Window`.
+	ErrRestartBudgetExceeded = errors.New("restart budget exceeded")
+)
+
+const (
+	defaultSupervisorBackoff    = time.Second
+	defaultSupervisorMaxBackoff = 30 * time.Second
+	defaultSupervisorWindow     = time.Minute
+)
+
+// SupervisionStrategy defines which services are restarted together when a
+// `Runnable` service stops running.
+type SupervisionStrategy string
+
+const (
+	// OneForOne restarts only the service that stopped running.
+	OneForOne SupervisionStrategy = "one-for-one"
+	// OneForAll restarts all services when one of them stops running.
+	OneForAll SupervisionStrategy = "one-for-all"
+	// RestForOne restarts the service that stopped running and all services
+	// started after it (which include its dependents).
+	RestForOne SupervisionStrategy = "rest-for-one"
+)
+
+// RestartPolicy defines when a `Runnable` service that stopped running gets
+// restarted.
+type RestartPolicy string
+
+const (
+	// RestartAlways restarts the service whenever its `Run` returns.
+	RestartAlways RestartPolicy = "always"
+	// RestartOnFailure restarts the service only if its `Run` returns an
+	// error (or panics).
+	RestartOnFailure RestartPolicy = "on-failure"
+	// RestartNever leaves the service stopped.
+	RestartNever RestartPolicy = "never"
+)
+
+// SupervisorOptions defines how the `ServiceStarter` restarts `Runnable`
+// services that stop running.
+type SupervisorOptions struct {
+	// Policy defines when the service is restarted. If empty,
+	// `RestartOnFailure` will be used.
+	Policy RestartPolicy
+
+	// Backoff is the delay before the first restart. It doubles for each
+	// consecutive restart, up to MaxBackoff. If the duration is 0, 1 second
+	// will be used.
+	Backoff time.Duration
+
+	// MaxBackoff is the maximum delay between restarts. If the duration is 0,
+	// 30 seconds will be used.
+	MaxBackoff time.Duration
+
+	// MaxRestarts is the number of restarts allowed within the Window. When
+	// it is exceeded, the `ServiceStarter` gives up and shuts down all
+	// services. 0 means no limit.
+	MaxRestarts int
+
+	// Window is the period considered by MaxRestarts. If the duration is 0,
+	// 1 minute will be used.
+	Window time.Duration
+}
+
+// Supervised is an abstraction for `Runnable` services that define their own
+// `SupervisorOptions`, replacing `ServiceStarterOptions.Supervisor`.
+type Supervised interface {
+	SupervisorOptions() SupervisorOptions
+}
+
+func (options SupervisorOptions) withDefaults() SupervisorOptions {
+	if options.Policy == "" {
+		options.Policy = RestartOnFailure
+	}
+	if options.Backoff == 0 {
+		options.Backoff = defaultSupervisorBackoff
+	}
+	if options.MaxBackoff == 0 {
+		options.MaxBackoff = defaultSupervisorMaxBackoff
+	}
+	if options.Window == 0 {
+		options.Window = defaultSupervisorWindow
+	}
+	return options
+}
+
+// runExit is reported when the `Run` of a service returns on its own.
+type runExit struct {
+	ctx       context.Context
+	node      *serviceNode
+	startedAt time.Time
+	err       error
+}
+
+// supervision keeps the restart history of a service.
+type supervision struct {
+	options      SupervisorOptions
+	restarts     []time.Time
+	backoff      time.Duration
+	runningSince time.Time
+}
+
+// startSupervisor starts the goroutine that restarts the `Runnable` services
+// that stop running.
+func (engineStarter *serviceStarter) startSupervisor() {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	engineStarter.supervisorCtx = ctx
+	engineStarter.supervisorCancel = cancelFunc
+	engineStarter.supervisorDone = make(chan struct{})
+	engineStarter.runExits = make(chan runExit)
+	go engineStarter.supervise(ctx, cancelFunc, engineStarter.supervisorDone)
+}
+
+// stopSupervisor stops restarting services and waits the supervisor to
+// finish any restart in progress.
+func (engineStarter *serviceStarter) stopSupervisor() {
+	if engineStarter.supervisorCancel == nil {
+		return
+	}
+	engineStarter.supervisorCancel()
+	<-engineStarter.supervisorDone
+}
+
+func (engineStarter *serviceStarter) supervise(ctx context.Context, cancelFunc context.CancelFunc, done chan struct{}) {
+	defer close(done)
+	// Releases the pending restarts and runs when the supervisor gives up.
+	defer cancelFunc()
+
+	supervisions := make(map[*serviceNode]*supervision)
+	restarts := make(chan *serviceNode)
+
+	// handleExit decides what to do with a service that stopped running.
+	// false is returned when the supervisor gave up.
+	handleExit := func(node *serviceNode, err error) bool {
+		if err != nil {
+			node.status.set(StateFailed, err)
+		} else {
+			node.status.set(StateStopped, nil)
+		}
+
+		sup, ok := supervisions[node]
+		if !ok {
+			sup = &supervision{
+				options: engineStarter.supervisorOptions(node.service),
+			}
+			sup.backoff = sup.options.Backoff
+			supervisions[node] = sup
+		}
+		switch {
+		case sup.options.Policy == RestartNever:
+			return true
+		case sup.options.Policy == RestartOnFailure && err == nil:
+			return true
+		}
+
+		// Drops the restarts that are out of the window.
+		now := time.Now()
+		recent := sup.restarts[:0]
+		for _, restartedAt := range sup.restarts {
+			if now.Sub(restartedAt) < sup.options.Window {
+				recent = append(recent, restartedAt)
+			}
+		}
+		sup.restarts = recent
+		if sup.options.MaxRestarts > 0 && len(sup.restarts) >= sup.options.MaxRestarts {
+			cause := ErrRestartBudgetExceeded
+			if err != nil {
+				cause = fmt.Errorf("%w: %s", ErrRestartBudgetExceeded, err)
+			}
+			engineStarter.escalate(newServiceError(node.service, PhaseRun, cause))
+			return false
+		}
+		sup.restarts = append(sup.restarts, now)
+
+		// A service that was running fine for a while starts over the backoff.
+		if !sup.runningSince.IsZero() && now.Sub(sup.runningSince) > sup.options.MaxBackoff {
+			sup.backoff = sup.options.Backoff
+		}
+		delay := sup.backoff
+		sup.backoff *= 2
+		if sup.backoff > sup.options.MaxBackoff {
+			sup.backoff = sup.options.MaxBackoff
+		}
+		time.AfterFunc(delay, func() {
+			select {
+			case restarts <- node:
+			case <-ctx.Done():
+			}
+		})
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case exit := <-engineStarter.runExits:
+			engineStarter.reporter.ReportEvent(exit.ctx, newLifecycleEvent(exit.node.service, PhaseRun, exit.startedAt, nil, exit.err))
+			if !handleExit(exit.node, exit.err) {
+				return
+			}
+		case node := <-restarts:
+			// A `Reload` does not run along with the restart.
+			engineStarter.restartMutex.Lock()
+			if node.status.State() == StateRunning {
+				// Already restarted along with another service.
+				engineStarter.restartMutex.Unlock()
+				continue
+			}
+			failed, err := engineStarter.restartServices(ctx, engineStarter.restartSet(node))
+			engineStarter.restartMutex.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				if !handleExit(failed, err) {
+					return
+				}
+				continue
+			}
+			if sup, ok := supervisions[node]; ok {
+				sup.runningSince = time.Now()
+			}
+		}
+	}
+}
+
+// supervisorOptions returns the `SupervisorOptions` for the service.
+func (engineStarter *serviceStarter) supervisorOptions(srv Service) SupervisorOptions {
+	var supervised Supervised
+	if As(srv, &supervised) {
+		return supervised.SupervisorOptions().withDefaults()
+	}
+	return engineStarter.options.Supervisor.withDefaults()
+}
+
+// restartSet returns the services that must be restarted along with the
+// given one, according to the `SupervisionStrategy`, in their start order.
+func (engineStarter *serviceStarter) restartSet(node *serviceNode) []*serviceNode {
+	switch engineStarter.options.Strategy {
+	case OneForAll, RestForOne:
+	default:
+		return []*serviceNode{node}
+	}
+
+	set := make([]*serviceNode, 0, len(engineStarter.nodes))
+	found := engineStarter.options.Strategy == OneForAll
+	for _, n := range engineStarter.nodes {
+		if n == node {
+			found = true
+		}
+		// Services the start did not reach yet are left for it.
+		if !found || (n != node && n.status.State() == StatePending) {
+			continue
+		}
+		set = append(set, n)
+	}
+	return set
+}
+
+// restartServices stops the services, in the reverse order, and starts them
+// again, without reloading their configuration. If a service fails to start,
+// it is returned along with the error.
+func (engineStarter *serviceStarter) restartServices(ctx context.Context, nodes []*serviceNode) (*serviceNode, error) {
+	// A failing stop does not prevent the service from being started again.
+	for i := len(nodes) - 1; i >= 0; i-- {
+		engineStarter.stopService(ctx, nodes[i])
+	}
+	for _, node := range nodes {
+		if ctx.Err() != nil {
+			return node, ctx.Err()
+		}
+		if _, err := engineStarter.startNode(ctx, node); err != nil {
+			return node, err
+		}
+	}
+	return nil, nil
+}
+
+// launchRun calls the `Run` of the service in a new goroutine. If it returns
+// before being cancelled by a `Stop`, the supervisor is notified.
+func (engineStarter *serviceStarter) launchRun(node *serviceNode, runnable Runnable) {
+	// The run outlives the start, so it keeps only the values of its context.
+	ctx, cancelFunc := context.WithCancel(engineStarter.detachedCtx)
+	done := make(chan struct{})
+	node.runCancel = cancelFunc
+	node.runDone = done
+
+	supervisorCtx := engineStarter.supervisorCtx
+	go func() {
+		startedAt := time.Now()
+		err := runSafely(ctx, runnable)
+		close(done)
+		if ctx.Err() != nil {
+			// Stopped on purpose.
+			return
+		}
+		select {
+		case engineStarter.runExits <- runExit{ctx, node, startedAt, err}:
+		case <-supervisorCtx.Done():
+		}
+	}()
+}
+
+// stopRun cancels the `Run` of the service, if any, and waits for it to
+// return.
+func stopRun(ctx context.Context, node *serviceNode) error {
+	if node.runCancel == nil {
+		return nil
+	}
+	node.runCancel()
+	select {
+	case <-node.runDone:
+		node.runCancel = nil
+		return nil
+	case <-ctx.Done():
+		return stopContextErr(ctx)
+	}
+}
+
+// runSafely calls the `Run` of the service, recovering from panics.
+func runSafely(ctx context.Context, runnable Runnable) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if eee, ok := r.(error); ok {
+			err = eee
+		} else {
+			err = ErrUnknownPanic
+		}
+	}()
+	return runnable.Run(ctx)
+}
+
+// escalate shuts down all services because a service could not be kept
+// running. The error is delivered through `Fatal`.
+func (engineStarter *serviceStarter) escalate(err error) {
+	engineStarter.chMutex.RLock()
+	fatalCh, fatalOnce := engineStarter.fatalCh, engineStarter.fatalOnce
+	engineStarter.chMutex.RUnlock()
+	fatalOnce.Do(func() {
+		engineStarter.chMutex.Lock()
+		engineStarter.fatalFired = true
+		engineStarter.chMutex.Unlock()
+		fatalCh <- err
+		go engineStarter.Stop(true)
+	})
+}
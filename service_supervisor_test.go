@@ -0,0 +1,261 @@
+package rscsrv_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.uber.org/atomic"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+type MockRunnableService struct {
+	name string
+	// failures is the number of runs that fail before the service keeps
+	// running. A negative value makes every run fail.
+	failures int32
+	// exitCleanly makes the failing runs return nil instead of an error.
+	exitCleanly bool
+	panics      bool
+	runs        atomic.Int32
+	running     atomic.Bool
+	options     *rscsrv.SupervisorOptions
+}
+
+func (service *MockRunnableService) Name() string {
+	return service.name
+}
+
+func (service *MockRunnableService) Run(ctx context.Context) error {
+	run := service.runs.Inc()
+	if service.failures < 0 || run <= service.failures {
+		switch {
+		case service.panics:
+			panic(errors.New("run panicked"))
+		case service.exitCleanly:
+			return nil
+		}
+		return errors.New("run failed")
+	}
+	service.running.Store(true)
+	defer service.running.Store(false)
+	<-ctx.Done()
+	return nil
+}
+
+type MockSupervisedService struct {
+	MockRunnableService
+}
+
+func (service *MockSupervisedService) SupervisorOptions() rscsrv.SupervisorOptions {
+	return *service.options
+}
+
+var _ = Describe("Supervisor", func() {
+	fastRestarts := rscsrv.SupervisorOptions{
+		Backoff:    time.Millisecond,
+		MaxBackoff: time.Millisecond * 5,
+	}
+
+	It("should restart a service that fails while running", func() {
+		service := &MockRunnableService{name: "consumer", failures: 2}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: fastRestarts,
+		}, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		Eventually(service.running.Load).Should(BeTrue())
+		Expect(service.runs.Load()).To(Equal(int32(3)))
+		Expect(stateOf(engineStarter, "consumer")).To(Equal(rscsrv.StateRunning))
+
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		Expect(service.running.Load()).To(BeFalse())
+		Expect(stateOf(engineStarter, "consumer")).To(Equal(rscsrv.StateStopped))
+	})
+
+	It("should report the failed runs of the service", func() {
+		reporter := &eventRecorder{}
+		service := &MockRunnableService{name: "consumer", failures: 2}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Reporter:   reporter,
+			Supervisor: fastRestarts,
+		}, service)
+		Expect(engineStarter.Start()).To(Succeed())
+		Eventually(service.running.Load).Should(BeTrue())
+		Expect(engineStarter.Stop(false)).To(Succeed())
+
+		runs := 0
+		for _, event := range reporter.events {
+			if event.Phase != rscsrv.PhaseRun {
+				continue
+			}
+			runs++
+			Expect(event.Name).To(Equal("consumer"))
+			Expect(event.Err).To(MatchError("run failed"))
+		}
+		Expect(runs).To(Equal(2))
+	})
+
+	It("should restart a service that panics while running", func() {
+		service := &MockRunnableService{name: "consumer", failures: 1, panics: true}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: fastRestarts,
+		}, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		Eventually(service.running.Load).Should(BeTrue())
+		Expect(service.runs.Load()).To(Equal(int32(2)))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should not restart a service that exits cleanly with the on-failure policy", func() {
+		service := &MockRunnableService{name: "scheduler", failures: 1, exitCleanly: true}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: fastRestarts,
+		}, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		Eventually(func() rscsrv.ServiceState {
+			return stateOf(engineStarter, "scheduler")
+		}).Should(Equal(rscsrv.StateStopped))
+		Consistently(service.runs.Load, time.Millisecond*50).Should(Equal(int32(1)))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should restart a service that exits cleanly with the always policy", func() {
+		service := &MockRunnableService{name: "scheduler", failures: 1, exitCleanly: true}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: rscsrv.SupervisorOptions{
+				Policy:     rscsrv.RestartAlways,
+				Backoff:    time.Millisecond,
+				MaxBackoff: time.Millisecond,
+			},
+		}, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		Eventually(service.running.Load).Should(BeTrue())
+		Expect(service.runs.Load()).To(Equal(int32(2)))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should leave a failed service with the never policy", func() {
+		service := &MockSupervisedService{
+			MockRunnableService: MockRunnableService{
+				name:     "consumer",
+				failures: 1,
+				options: &rscsrv.SupervisorOptions{
+					Policy: rscsrv.RestartNever,
+				},
+			},
+		}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: fastRestarts,
+		}, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		Eventually(func() rscsrv.ServiceState {
+			return stateOf(engineStarter, "consumer")
+		}).Should(Equal(rscsrv.StateFailed))
+		Consistently(service.runs.Load, time.Millisecond*50).Should(Equal(int32(1)))
+		Expect(engineStarter.Snapshot().Services[0].LastError).To(Equal("run failed"))
+		Expect(engineStarter.Stop(false)).To(Succeed())
+	})
+
+	It("should shut down everything when the restart budget is exceeded", func() {
+		recorder := &orderRecorder{}
+		database := &MockDependentService{name: "database", recorder: recorder}
+		service := &MockRunnableService{name: "consumer", failures: -1}
+		engineStarter := rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+			Supervisor: rscsrv.SupervisorOptions{
+				Backoff:     time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+				MaxRestarts: 3,
+				Window:      time.Minute,
+			},
+		}, database, service)
+		Expect(engineStarter.Start()).To(Succeed())
+
+		var err error
+		Eventually(engineStarter.Fatal()).Should(Receive(&err))
+		Expect(errors.Is(err, rscsrv.ErrRestartBudgetExceeded)).To(BeTrue())
+		Expect(err.(*rscsrv.ServiceError).Service).To(Equal("consumer"))
+		Expect(err.(*rscsrv.ServiceError).Phase).To(Equal(rscsrv.PhaseRun))
+		Expect(service.runs.Load()).To(Equal(int32(4)))
+
+		engineStarter.Wait()
+		Expect(database.stopped.Load()).To(BeTrue())
+	})
+
+	Context("Strategies", func() {
+		// countEvents counts how many times the event was recorded.
+		countEvents := func(recorder *orderRecorder, event string) int {
+			count := 0
+			for _, e := range recorder.Events() {
+				if e == event {
+					count++
+				}
+			}
+			return count
+		}
+
+		newStarter := func(strategy rscsrv.SupervisionStrategy, recorder *orderRecorder, consumer *MockRunnableService) rscsrv.ServiceStarter {
+			return rscsrv.NewServiceStarterWithOptions(rscsrv.ServiceStarterOptions{
+				Supervisor: fastRestarts,
+				Strategy:   strategy,
+			},
+				&MockDependentService{name: "database", recorder: recorder},
+				consumer,
+				&MockDependentService{name: "repository", recorder: recorder},
+			)
+		}
+
+		It("should restart only the failed service with one-for-one", func() {
+			recorder := &orderRecorder{}
+			consumer := &MockRunnableService{name: "consumer", failures: 1}
+			engineStarter := newStarter(rscsrv.OneForOne, recorder, consumer)
+			Expect(engineStarter.Start()).To(Succeed())
+
+			Eventually(consumer.running.Load).Should(BeTrue())
+			Expect(recorder.Events()).To(Equal([]string{"start database", "start repository"}))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+
+		It("should restart all services with one-for-all", func() {
+			recorder := &orderRecorder{}
+			consumer := &MockRunnableService{name: "consumer", failures: 1}
+			engineStarter := newStarter(rscsrv.OneForAll, recorder, consumer)
+			Expect(engineStarter.Start()).To(Succeed())
+
+			Eventually(consumer.running.Load).Should(BeTrue())
+			Expect(recorder.Events()).To(Equal([]string{
+				"start database",
+				"start repository",
+				"stop repository",
+				"stop database",
+				"start database",
+				"start repository",
+			}))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+
+		It("should restart the services started after the failed one with rest-for-one", func() {
+			recorder := &orderRecorder{}
+			consumer := &MockRunnableService{name: "consumer", failures: 1}
+			engineStarter := newStarter(rscsrv.RestForOne, recorder, consumer)
+			Expect(engineStarter.Start()).To(Succeed())
+
+			Eventually(consumer.running.Load).Should(BeTrue())
+			Expect(recorder.Events()).To(Equal([]string{
+				"start database",
+				"start repository",
+				"stop repository",
+				"start repository",
+			}))
+			Expect(countEvents(recorder, "start database")).To(Equal(1))
+			Expect(engineStarter.Stop(false)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package rscsrv
+
+import "reflect"
+
+// Unwrap returns the service wrapped by srv, or nil if srv is not a
+// `Wrapper`.
+func Unwrap(srv Service) Service {
+	wrapper, ok := srv.(Wrapper)
+	if !ok {
+		return nil
+	}
+	return wrapper.Unwrap()
+}
+
+// As finds the first service, in the chain of wrapped services that starts
+// at srv, that implements the interface target points to. If found, target
+// is set to it and true is returned.
+//
+// As panics if target is not a non-nil pointer to an interface.
+func As(srv Service, target interface{}) bool {
+	if target == nil {
+		panic("rscsrv: target cannot be nil")
+	}
+	val := reflect.ValueOf(target)
+	typ := val.Type()
+	if typ.Kind() != reflect.Ptr || val.IsNil() {
+		panic("rscsrv: target must be a non-nil pointer")
+	}
+	targetType := typ.Elem()
+	if targetType.Kind() != reflect.Interface {
+		panic("rscsrv: *target must be an interface")
+	}
+	found := findService(srv, func(srv Service) bool {
+		return reflect.TypeOf(srv).AssignableTo(targetType)
+	})
+	if found == nil {
+		return false
+	}
+	val.Elem().Set(reflect.ValueOf(found))
+	return true
+}
+
+// findService returns the first service, in the chain of wrapped services
+// that starts at srv, that matches.
+func findService(srv Service, match func(srv Service) bool) Service {
+	for srv != nil {
+		if match(srv) {
+			return srv
+		}
+		srv = Unwrap(srv)
+	}
+	return nil
+}
+
+// findStartable returns the service that starts srv: the first one that is
+// `Startable` or `StartableWithContext`.
+func findStartable(srv Service) Service {
+	return findService(srv, func(srv Service) bool {
+		switch srv.(type) {
+		case Startable, StartableWithContext:
+			return true
+		}
+		return false
+	})
+}
+
+// findStoppable returns the service that stops srv: the first one that is
+// `Stoppable` or `StoppableWithContext`.
+func findStoppable(srv Service) Service {
+	return findService(srv, func(srv Service) bool {
+		switch srv.(type) {
+		case Stoppable, StoppableWithContext:
+			return true
+		}
+		return false
+	})
+}
+
+// asConfigurable returns the `Configurable` of srv, if any.
+func asConfigurable(srv Service) (Configurable, bool) {
+	var configurable Configurable
+	ok := As(srv, &configurable)
+	return configurable, ok
+}
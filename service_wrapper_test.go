@@ -0,0 +1,101 @@
+package rscsrv_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lab259/go-rscsrv"
+)
+
+// mockWrapper is a decorator that implements nothing but `Wrapper`.
+type mockWrapper struct {
+	rscsrv.Service
+}
+
+func (wrapper *mockWrapper) Unwrap() rscsrv.Service {
+	return wrapper.Service
+}
+
+var _ = Describe("Wrapper", func() {
+	It("should unwrap the wrapped service", func() {
+		service := &MockDependentService{name: "database", recorder: &orderRecorder{}}
+		wrapper := &mockWrapper{service}
+		Expect(rscsrv.Unwrap(wrapper)).To(BeIdenticalTo(service))
+		Expect(rscsrv.Unwrap(service)).To(BeNil())
+	})
+
+	It("should find the abstractions of the wrapped services", func() {
+		service := newMockHealthService("database", false, nil)
+		wrapper := &mockWrapper{rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{})}
+
+		var checker rscsrv.HealthChecker
+		Expect(rscsrv.As(wrapper, &checker)).To(BeTrue())
+		Expect(checker).To(BeIdenticalTo(service))
+
+		// The wrapper comes first.
+		var startable rscsrv.Startable
+		Expect(rscsrv.As(wrapper, &startable)).To(BeTrue())
+		Expect(startable).To(BeIdenticalTo(wrapper.Service))
+
+		var runnable rscsrv.Runnable
+		Expect(rscsrv.As(wrapper, &runnable)).To(BeFalse())
+		Expect(runnable).To(BeNil())
+	})
+
+	It("should panic when the target is not a pointer to an interface", func() {
+		service := &MockDependentService{name: "database", recorder: &orderRecorder{}}
+		Expect(func() { rscsrv.As(service, nil) }).To(Panic())
+		Expect(func() { rscsrv.As(service, service) }).To(Panic())
+		var target MockDependentService
+		Expect(func() { rscsrv.As(service, &target) }).To(Panic())
+	})
+
+	It("should keep the abstractions of the services wrapped by a StartRetrier", func() {
+		recorder := &orderRecorder{}
+		api := newMockHealthService("api", false, errors.New("unhealthy"))
+		api.recorder = recorder
+		api.dependsOn = []string{"database"}
+		engineStarter := rscsrv.QuietServiceStarter(
+			rscsrv.NewStartRetrier(api, rscsrv.StartRetrierOptions{
+				Reporter: &retrierMockReporter{},
+			}),
+			&MockDependentService{name: "database", recorder: recorder},
+		)
+		Expect(engineStarter.Start()).To(Succeed())
+		Expect(recorder.Events()).To(Equal([]string{"start database", "start api"}))
+
+		report := engineStarter.CheckHealth(context.Background())
+		Expect(report.Status).To(Equal(rscsrv.HealthStatusDegraded))
+		Expect(report.Services[0].Critical).To(BeFalse())
+		Expect(report.Services[0].Error).To(Equal("unhealthy"))
+		Expect(api.checks.Load()).To(Equal(int32(1)))
+
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		Expect(api.stopped.Load()).To(BeTrue())
+	})
+
+	It("should keep a configurable service Configurable when wrapped by a StartRetrier", func() {
+		service := &retrierMockService{}
+		retrier := rscsrv.NewStartRetrier(service, rscsrv.StartRetrierOptions{})
+		Expect(rscsrv.Unwrap(retrier)).To(BeIdenticalTo(service))
+
+		configurable, ok := retrier.(rscsrv.Configurable)
+		Expect(ok).To(BeTrue())
+		_, err := configurable.LoadConfiguration()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(service.loadConfCount).To(Equal(1))
+	})
+
+	It("should run the services wrapped by any Wrapper", func() {
+		service := &MockRunnableService{name: "worker"}
+		engineStarter := rscsrv.QuietServiceStarter(&mockWrapper{service})
+		Expect(engineStarter.Start()).To(Succeed())
+		Eventually(service.running.Load, time.Second).Should(BeTrue())
+		Expect(engineStarter.Stop(false)).To(Succeed())
+		Expect(service.running.Load()).To(BeFalse())
+	})
+})
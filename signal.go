@@ -1,44 +1,227 @@
 package rscsrv
 
 import (
+	"context"
 	"os"
 	"os/signal"
+	"sync"
 )
 
+// SignalAction is what a `SignalStarter` does when it receives a signal.
+type SignalAction string
+
+const (
+	// SignalStop stops all services. A second stop signal received while
+	// stopping forces the process to exit.
+	SignalStop SignalAction = "stop"
+	// SignalReload reloads the configuration of the services (see
+	// `ServiceStarter.Reload`).
+	SignalReload SignalAction = "reload"
+	// SignalDump reports a `Snapshot` of the services through the
+	// `SnapshotReporter`.
+	SignalDump SignalAction = "dump"
+)
+
+// DefaultForceExitCode is the exit code used when the process is forced to
+// exit by a second stop signal.
+const DefaultForceExitCode = 130
+
+// SnapshotReporter is the abstraction for reporting the state of the
+// services when a `SignalDump` is received.
+type SnapshotReporter interface {
+	ReportSnapshot(snapshot Snapshot)
+}
+
+// ReloadReporter is the abstraction for reporting the result of the reload
+// taken when a `SignalReload` is received.
+type ReloadReporter interface {
+	// ReportReload is called after the reload with the error returned by
+	// `ServiceStarter.Reload`, if any.
+	ReportReload(err error)
+}
+
+// SignalStarterOptions configures a `SignalStarter`.
+type SignalStarterOptions struct {
+	// Actions maps the signals to the actions taken when they are received.
+	// If empty, interrupt and termination signals stop the services, SIGHUP
+	// reloads the configuration and SIGUSR1 dumps a snapshot (on systems that
+	// support them).
+	Actions map[os.Signal]SignalAction
+
+	// Reporter receives the snapshots dumped by `SignalDump`. If nil, the
+	// reporter of the starter will be used when it is a `SnapshotReporter`.
+	Reporter SnapshotReporter
+
+	// ForceExitCode is the exit code used when a second stop signal is
+	// received while stopping. If zero, `DefaultForceExitCode` will be used.
+	ForceExitCode int
+
+	// Exit is called to force the process to exit. If nil, `os.Exit` will
+	// be used.
+	Exit func(code int)
+}
+
 type signalServiceStarter struct {
 	ServiceStarter
-	signalList []os.Signal
+	options SignalStarterOptions
 
 	signals chan os.Signal
+
+	mutex       sync.Mutex
+	done        chan struct{}
+	releaseOnce *sync.Once
+	stopping    bool
 }
 
+// SignalStarter returns a `ServiceStarter` that stops all services when one
+// of the given signals is received. If no signal is given, `os.Interrupt`
+// will be used.
 func SignalStarter(serviceStarter ServiceStarter, signals ...os.Signal) ServiceStarter {
 	if len(signals) == 0 {
 		signals = []os.Signal{os.Interrupt}
 	}
+	actions := make(map[os.Signal]SignalAction, len(signals))
+	for _, sig := range signals {
+		actions[sig] = SignalStop
+	}
+	return NewSignalStarter(serviceStarter, SignalStarterOptions{
+		Actions: actions,
+	})
+}
+
+// NewSignalStarter returns a `ServiceStarter` that takes the actions
+// configured in the options when receiving signals.
+func NewSignalStarter(serviceStarter ServiceStarter, options SignalStarterOptions) ServiceStarter {
+	if len(options.Actions) == 0 {
+		options.Actions = defaultSignalActions()
+	}
+	if options.ForceExitCode == 0 {
+		options.ForceExitCode = DefaultForceExitCode
+	}
+	if options.Exit == nil {
+		options.Exit = os.Exit
+	}
 	return &signalServiceStarter{
 		ServiceStarter: serviceStarter,
-		signalList:     signals,
+		options:        options,
 		signals:        make(chan os.Signal, 1),
 	}
 }
 
 func (starter *signalServiceStarter) Start() error {
-	signal.Notify(starter.signals, starter.signalList...)
-	go func() {
-		<-starter.signals
-		starter.Stop(true)
-	}()
-	return starter.ServiceStarter.Start()
+	return starter.StartWithContext(context.Background())
+}
+
+func (starter *signalServiceStarter) StartWithContext(ctx context.Context) error {
+	signals := make([]os.Signal, 0, len(starter.options.Actions))
+	for sig := range starter.options.Actions {
+		signals = append(signals, sig)
+	}
+
+	starter.mutex.Lock()
+	done := make(chan struct{})
+	starter.done = done
+	starter.releaseOnce = &sync.Once{}
+	starter.stopping = false
+	starter.mutex.Unlock()
+
+	signal.Notify(starter.signals, signals...)
+	go starter.handleSignals(done)
+	return starter.ServiceStarter.StartWithContext(ctx)
+}
+
+// handleSignals takes the action of each signal received until the signals
+// are released.
+func (starter *signalServiceStarter) handleSignals(done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case sig := <-starter.signals:
+			handleSignal(starter, starter.options.Actions[sig], starter.options.Reporter, starter.stopOrExit)
+		}
+	}
+}
+
+// stopOrExit stops all services in background, so a second stop signal can
+// still be handled. If already stopping, it forces the process to exit.
+func (starter *signalServiceStarter) stopOrExit() {
+	starter.mutex.Lock()
+	stopping := starter.stopping
+	starter.stopping = true
+	starter.mutex.Unlock()
+	if stopping {
+		starter.options.Exit(starter.options.ForceExitCode)
+		return
+	}
+	go starter.Stop(true)
+}
+
+// starterReporter returns the reporter of the wrapped starter.
+func (starter *signalServiceStarter) starterReporter() ServiceStarterReporter {
+	return reporterOf(starter.ServiceStarter)
+}
+
+// reporterOf returns the reporter of the starter. If the starter does not
+// expose it, `DefaultColorStarterReporter` is returned.
+func reporterOf(starter ServiceStarter) ServiceStarterReporter {
+	if withReporter, ok := starter.(interface {
+		starterReporter() ServiceStarterReporter
+	}); ok {
+		return withReporter.starterReporter()
+	}
+	return DefaultColorStarterReporter
+}
+
+// handleSignal takes the action on the starter. How the services are stopped
+// by `SignalStop` is up to stop. The snapshots go to reporter or, if nil, to
+// the reporter of the starter, as the result of the reloads.
+func handleSignal(starter ServiceStarter, action SignalAction, reporter SnapshotReporter, stop func()) {
+	switch action {
+	case SignalStop:
+		stop()
+	case SignalReload:
+		err := starter.Reload()
+		if reloadReporter, ok := reporterOf(starter).(ReloadReporter); ok {
+			reloadReporter.ReportReload(err)
+		}
+	case SignalDump:
+		if reporter == nil {
+			reporter, _ = reporterOf(starter).(SnapshotReporter)
+		}
+		if reporter != nil {
+			reporter.ReportSnapshot(starter.Snapshot())
+		}
+	}
 }
 
 func (starter *signalServiceStarter) Stop(keepGoing bool) error {
-	err := starter.ServiceStarter.Stop(keepGoing)
-	if err != nil {
-		return err
+	return starter.StopWithContext(context.Background(), keepGoing)
+}
+
+func (starter *signalServiceStarter) StopWithContext(ctx context.Context, keepGoing bool) error {
+	starter.mutex.Lock()
+	starter.stopping = true
+	starter.mutex.Unlock()
+
+	err := starter.ServiceStarter.StopWithContext(ctx, keepGoing)
+	starter.release()
+	return err
+}
+
+// release stops receiving signals and finishes the goroutine handling them.
+func (starter *signalServiceStarter) release() {
+	starter.mutex.Lock()
+	done, releaseOnce := starter.done, starter.releaseOnce
+	starter.mutex.Unlock()
+	if releaseOnce == nil {
+		// Never started.
+		return
 	}
-	close(starter.signals)
-	return nil
+	releaseOnce.Do(func() {
+		signal.Stop(starter.signals)
+		close(done)
+	})
 }
 
 func (starter *signalServiceStarter) Wait() {
@@ -0,0 +1,12 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package rscsrv
+
+import "os"
+
+func defaultSignalActions() map[os.Signal]SignalAction {
+	return map[os.Signal]SignalAction{
+		os.Interrupt: SignalStop,
+	}
+}
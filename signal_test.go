@@ -2,6 +2,7 @@ package rscsrv
 
 import (
 	"context"
+	"errors"
 	"os"
 	"time"
 
@@ -56,6 +57,53 @@ func (service *MockService) Stop() error {
 	return service.errStop
 }
 
+// testSignal is a signal that is never sent by the system.
+type testSignal string
+
+func (sig testSignal) String() string {
+	return string(sig)
+}
+
+func (sig testSignal) Signal() {}
+
+const (
+	testSignalReload = testSignal("reload")
+	testSignalDump   = testSignal("dump")
+)
+
+type reloadableMockService struct {
+	MockService
+	loads     atomic.Int32
+	errReload error
+}
+
+func (service *reloadableMockService) LoadConfiguration() (interface{}, error) {
+	if service.loads.Inc() > 1 {
+		return nil, service.errReload
+	}
+	return nil, nil
+}
+
+type snapshotRecorder struct {
+	snapshots chan Snapshot
+}
+
+func (reporter *snapshotRecorder) ReportSnapshot(snapshot Snapshot) {
+	reporter.snapshots <- snapshot
+}
+
+// signalStarterReporter is a `ServiceStarterReporter` that records the
+// snapshots and the reloads.
+type signalStarterReporter struct {
+	NopStarterReporter
+	snapshotRecorder
+	reloads chan error
+}
+
+func (reporter *signalStarterReporter) ReportReload(err error) {
+	reporter.reloads <- err
+}
+
 var _ = Describe("Signal", func() {
 	It("should stop services when receiving a signal", func() {
 		// Start service1 and service2;
@@ -97,4 +145,97 @@ var _ = Describe("Signal", func() {
 		}()
 		Expect(starter.Start()).To(Equal(context.Canceled))
 	}, 1)
+
+	Context("Actions", func() {
+		newSignalStarter := func(exit func(int), services ...Service) (ServiceStarter, *signalServiceStarter, *snapshotRecorder) {
+			reporter := &snapshotRecorder{snapshots: make(chan Snapshot, 1)}
+			starter := NewSignalStarter(NewServiceStarter(&NopStarterReporter{}, services...), SignalStarterOptions{
+				Actions: map[os.Signal]SignalAction{
+					os.Interrupt:     SignalStop,
+					testSignalReload: SignalReload,
+					testSignalDump:   SignalDump,
+				},
+				Reporter: reporter,
+				Exit:     exit,
+			})
+			return starter, starter.(*signalServiceStarter), reporter
+		}
+
+		It("should reload the configuration when receiving a reload signal", func() {
+			service := &reloadableMockService{}
+			starter, signalStarter, _ := newSignalStarter(nil, service)
+			Expect(starter.Start()).To(Succeed())
+			Expect(service.loads.Load()).To(Equal(int32(1)))
+
+			signalStarter.signals <- testSignalReload
+			Eventually(service.loads.Load).Should(Equal(int32(2)))
+			Expect(starter.Stop(true)).To(Succeed())
+		})
+
+		It("should dump a snapshot when receiving a dump signal", func() {
+			starter, signalStarter, reporter := newSignalStarter(nil, &MockService{name: "service1"})
+			Expect(starter.Start()).To(Succeed())
+
+			signalStarter.signals <- testSignalDump
+			var snapshot Snapshot
+			Eventually(reporter.snapshots).Should(Receive(&snapshot))
+			Expect(snapshot.Services).To(HaveLen(1))
+			Expect(snapshot.Services[0].Name).To(Equal("service1"))
+			Expect(snapshot.Services[0].State).To(Equal(StateRunning))
+			Expect(starter.Stop(true)).To(Succeed())
+		})
+
+		It("should report through the reporter of the starter", func() {
+			errReload := errors.New("reload error")
+			reporter := &signalStarterReporter{
+				snapshotRecorder: snapshotRecorder{snapshots: make(chan Snapshot, 1)},
+				reloads:          make(chan error, 1),
+			}
+			service := &reloadableMockService{errReload: errReload}
+			service.name = "service1"
+			starter := NewSignalStarter(NewServiceStarter(reporter, service), SignalStarterOptions{
+				Actions: map[os.Signal]SignalAction{
+					testSignalReload: SignalReload,
+					testSignalDump:   SignalDump,
+				},
+			})
+			Expect(starter.Start()).To(Succeed())
+			signalStarter := starter.(*signalServiceStarter)
+
+			signalStarter.signals <- testSignalDump
+			var snapshot Snapshot
+			Eventually(reporter.snapshots).Should(Receive(&snapshot))
+			Expect(snapshot.Services[0].Name).To(Equal("service1"))
+
+			signalStarter.signals <- testSignalReload
+			var err error
+			Eventually(reporter.reloads).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(errReload.Error()))
+			Expect(starter.Stop(true)).To(Succeed())
+		})
+
+		It("should force the exit when receiving a second stop signal while stopping", func() {
+			exitCode := make(chan int, 1)
+			service := &MockService{stopDuration: time.Millisecond * 100}
+			starter, signalStarter, _ := newSignalStarter(func(code int) {
+				exitCode <- code
+			}, service)
+			Expect(starter.Start()).To(Succeed())
+
+			signalStarter.signals <- os.Interrupt
+			Eventually(service.stopped.Load).Should(BeTrue())
+			signalStarter.signals <- os.Interrupt
+			Eventually(exitCode).Should(Receive(Equal(DefaultForceExitCode)))
+			starter.Wait()
+		})
+
+		It("should stop handling signals when stopped", func() {
+			starter, signalStarter, _ := newSignalStarter(nil, &MockService{})
+			Expect(starter.Start()).To(Succeed())
+			Expect(starter.Stop(true)).To(Succeed())
+			Expect(signalStarter.done).To(BeClosed())
+			Expect(starter.Stop(true)).To(Succeed())
+		})
+	})
 })
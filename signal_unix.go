@@ -0,0 +1,18 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package rscsrv
+
+import (
+	"os"
+	"syscall"
+)
+
+func defaultSignalActions() map[os.Signal]SignalAction {
+	return map[os.Signal]SignalAction{
+		os.Interrupt:    SignalStop,
+		syscall.SIGTERM: SignalStop,
+		syscall.SIGHUP:  SignalReload,
+		syscall.SIGUSR1: SignalDump,
+	}
+}
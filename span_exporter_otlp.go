@@ -0,0 +1,170 @@
+package rscsrv
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// OTLPFileExporter is a `SpanExporter` that writes the spans as
+// OTLP-compatible JSON, one `ExportTraceServiceRequest` per line, which can be
+// loaded into tracing backends (eg: Jaeger, through the OpenTelemetry
+// Collector `otlpjsonfile` receiver).
+type OTLPFileExporter struct {
+	mutex       sync.Mutex
+	w           io.Writer
+	closer      io.Closer
+	serviceName string
+}
+
+// NewOTLPFileExporter returns an `OTLPFileExporter` that appends the spans to
+// the file, creating it if needed. serviceName identifies the application in
+// the traces; if empty, "rscsrv" will be used.
+func NewOTLPFileExporter(filename, serviceName string) (*OTLPFileExporter, error) {
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	exporter := NewOTLPWriterExporter(f, serviceName)
+	exporter.closer = f
+	return exporter, nil
+}
+
+// NewOTLPWriterExporter returns an `OTLPFileExporter` that writes the spans
+// to w.
+func NewOTLPWriterExporter(w io.Writer, serviceName string) *OTLPFileExporter {
+	if serviceName == "" {
+		serviceName = "rscsrv"
+	}
+	return &OTLPFileExporter{
+		w:           w,
+		serviceName: serviceName,
+	}
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeError  = 2
+)
+
+// ExportSpans writes the spans as a single line.
+func (exporter *OTLPFileExporter) ExportSpans(ctx context.Context, spans []Span) error {
+	otlpSpans := make([]otlpSpan, len(spans))
+	for i, span := range spans {
+		otlpSpans[i] = otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+		if !span.ParentSpanID.IsZero() {
+			otlpSpans[i].ParentSpanID = span.ParentSpanID.String()
+		}
+		if span.Err != nil {
+			otlpSpans[i].Status = otlpStatus{
+				Code:    otlpStatusCodeError,
+				Message: span.Err.Error(),
+			}
+		}
+	}
+
+	data, err := json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes(map[string]string{
+					"service.name": exporter.serviceName,
+				}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/lab259/go-rscsrv"},
+				Spans: otlpSpans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	_, err = exporter.w.Write(append(data, '\n'))
+	return err
+}
+
+// otlpAttributes converts the attributes, sorted by key.
+func otlpAttributes(attributes map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlpKeyValue, len(keys))
+	for i, key := range keys {
+		kvs[i] = otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: attributes[key]}}
+	}
+	return kvs
+}
+
+// Close closes the file, if the exporter was created by
+// `NewOTLPFileExporter`.
+func (exporter *OTLPFileExporter) Close() error {
+	if exporter.closer == nil {
+		return nil
+	}
+	return exporter.closer.Close()
+}